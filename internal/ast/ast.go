@@ -1,6 +1,10 @@
 package ast
 
-import "github.com/sedexdev/go-interpreter/internal/token"
+import (
+	"math/big"
+
+	"github.com/sedexdev/go-interpreter/internal/token"
+)
 
 /*
 ==========
@@ -8,8 +12,11 @@ Interfaces
 ==========
 */
 
-// Node interface for all other nodes to implement
+// Node interface for all other nodes to implement. GetToken
+// gives the token a node starts with, which locates the node
+// in the source code
 type Node interface {
+	GetToken() token.Token
 }
 
 // Statement interface for producing statements
@@ -36,15 +43,63 @@ type Program struct {
 	Statements []Statement
 }
 
-// VariableStatement defines a variable declaration statement
+// GetToken returns the token of the first statement in the program
+func (program *Program) GetToken() token.Token {
+	if len(program.Statements) > 0 {
+		return program.Statements[0].GetToken()
+	}
+	return token.Token{}
+}
+
+// VariableStatement defines a variable declaration statement. Type is
+// empty for untyped declarations and reassignments such as "x = 1".
+// Value is nil for declarations without an initialiser, which give the
+// variable the zero value of its type. Struct points to the definition
+// when the variable has a struct type
 type VariableStatement struct {
-	Token token.Token
-	Name  *Identifier
-	Value Expression
+	Token    token.Token
+	Name     *Identifier
+	Value    Expression
+	Type     string
+	Constant bool
+	Struct   *StructStatement
 }
 
 func (varStat *VariableStatement) statementNode() {}
 
+func (varStat *VariableStatement) GetToken() token.Token { return varStat.Token }
+
+// StructStatement defines a struct type and its fields
+type StructStatement struct {
+	Token  token.Token
+	Name   *Identifier
+	Fields []*StructField
+}
+
+func (structStat *StructStatement) statementNode() {}
+
+func (structStat *StructStatement) GetToken() token.Token { return structStat.Token }
+
+// StructField is a single field of a struct. Struct points to the
+// definition when the field itself has a struct type
+type StructField struct {
+	Token  token.Token
+	Name   *Identifier
+	Type   string
+	Struct *StructStatement
+}
+
+// MemberAssignStatement assigns a value to a field of a struct variable
+type MemberAssignStatement struct {
+	Token  token.Token
+	Target *MemberExpression
+	Value  Expression
+}
+
+func (memberStat *MemberAssignStatement) statementNode() {}
+
+func (memberStat *MemberAssignStatement) GetToken() token.Token { return memberStat.Token }
+
 // ExpressionStatement defines an expression to be evaluated
 type ExpressionStatement struct {
 	Token      token.Token
@@ -53,6 +108,8 @@ type ExpressionStatement struct {
 
 func (expStat *ExpressionStatement) statementNode() {}
 
+func (expStat *ExpressionStatement) GetToken() token.Token { return expStat.Token }
+
 // BlockStatement for if/else statement and while loops
 type BlockStatement struct {
 	Token      token.Token
@@ -61,6 +118,8 @@ type BlockStatement struct {
 
 func (BlockStat *BlockStatement) statementNode() {}
 
+func (BlockStat *BlockStatement) GetToken() token.Token { return BlockStat.Token }
+
 // IfStatement struct to represent if/else statements
 type IfStatement struct {
 	Token        token.Token
@@ -71,6 +130,8 @@ type IfStatement struct {
 
 func (ifStat *IfStatement) statementNode() {}
 
+func (ifStat *IfStatement) GetToken() token.Token { return ifStat.Token }
+
 // WhileStatement struct to represent while loops
 type WhileStatement struct {
 	Token     token.Token
@@ -80,14 +141,46 @@ type WhileStatement struct {
 
 func (whileStat *WhileStatement) statementNode() {}
 
-// PrintStatement struct for representing print statements
+func (whileStat *WhileStatement) GetToken() token.Token { return whileStat.Token }
+
+// PrintStatement struct for representing print statements. Newline
+// is false for print_inline, which doesn't end the line
 type PrintStatement struct {
-	Token  token.Token
-	Values []Expression
+	Token   token.Token
+	Values  []Expression
+	Newline bool
 }
 
 func (printStat *PrintStatement) statementNode() {}
 
+func (printStat *PrintStatement) GetToken() token.Token { return printStat.Token }
+
+// ReadStatement struct for representing read statements, which
+// read values from input into variables, e.g. read x, y;
+type ReadStatement struct {
+	Token token.Token
+	Names []*Identifier
+}
+
+func (readStat *ReadStatement) statementNode() {}
+
+func (readStat *ReadStatement) GetToken() token.Token { return readStat.Token }
+
+// AssertStatement struct for representing assertions, which stop the
+// program when their condition is false, e.g. assert(x > 0, "message");
+// Source is the text of the condition and Start is its first token
+type AssertStatement struct {
+	Token     token.Token
+	Condition Expression
+	Message   Expression
+	Source    string
+	Start     token.Token
+}
+
+func (assertStat *AssertStatement) statementNode() {}
+
+func (assertStat *AssertStatement) GetToken() token.Token { return assertStat.Token }
+
 // Identifier struct representing a C-- identifier
 type Identifier struct {
 	Token token.Token
@@ -96,14 +189,83 @@ type Identifier struct {
 
 func (id *Identifier) expressionNode() {}
 
-// Integer struct representing a C-- integer
+func (id *Identifier) GetToken() token.Token { return id.Token }
+
+// Integer struct representing a C-- integer. Literals too large
+// for an int64 are kept in Big, for use in big integer mode
 type Integer struct {
 	Token token.Token
 	Value int64
+	Big   *big.Int
 }
 
 func (integer *Integer) expressionNode() {}
 
+func (integer *Integer) GetToken() token.Token { return integer.Token }
+
+// Float struct representing a C-- double literal
+type Float struct {
+	Token token.Token
+	Value float64
+}
+
+func (float *Float) expressionNode() {}
+
+func (float *Float) GetToken() token.Token { return float.Token }
+
+// Boolean struct representing a C-- true or false literal
+type Boolean struct {
+	Token token.Token
+	Value bool
+}
+
+func (boolean *Boolean) expressionNode() {}
+
+func (boolean *Boolean) GetToken() token.Token { return boolean.Token }
+
+// String struct representing a C-- string literal
+type String struct {
+	Token token.Token
+	Value string
+}
+
+func (str *String) expressionNode() {}
+
+func (str *String) GetToken() token.Token { return str.Token }
+
+// MemberExpression defines access to a field of a struct, e.g. p.x
+type MemberExpression struct {
+	Token  token.Token
+	Object Expression
+	Field  *Identifier
+}
+
+func (member *MemberExpression) expressionNode() {}
+
+func (member *MemberExpression) GetToken() token.Token { return member.Token }
+
+// PrefixExpression defines an operator applied to a single operand, e.g. -x
+type PrefixExpression struct {
+	Token    token.Token
+	Operator string
+	Right    Expression
+}
+
+func (prefix *PrefixExpression) expressionNode() {}
+
+func (prefix *PrefixExpression) GetToken() token.Token { return prefix.Token }
+
+// CallExpression defines a call to a builtin function, e.g. printf("%d", n)
+type CallExpression struct {
+	Token     token.Token
+	Function  *Identifier
+	Arguments []Expression
+}
+
+func (call *CallExpression) expressionNode() {}
+
+func (call *CallExpression) GetToken() token.Token { return call.Token }
+
 // InfixExpression defines an infix expression to be evaluated
 type InfixExpression struct {
 	Token    token.Token
@@ -113,3 +275,5 @@ type InfixExpression struct {
 }
 
 func (infix *InfixExpression) expressionNode() {}
+
+func (infix *InfixExpression) GetToken() token.Token { return infix.Token }
@@ -0,0 +1,452 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/sedexdev/go-interpreter/internal/ast"
+	"github.com/sedexdev/go-interpreter/internal/symbol"
+)
+
+// Names of the C-- types known to the checker. An empty type
+// means the type of an expression couldn't be worked out, e.g.
+// a variable that is used before it is assigned
+const (
+	UNKNOWN = ""
+	INT     = "int"
+	DOUBLE  = "double"
+	BOOL    = "bool"
+	STRING  = "string"
+	VOID    = "void"
+)
+
+// Map of builtin functions to the types they return
+var builtinTypes = map[string]string{
+	"printf":     INT,
+	"input":      INT,
+	"eof":        BOOL,
+	"exit":       VOID,
+	"argc":       INT,
+	"argi":       INT,
+	"env":        STRING,
+	"isqrt":      INT,
+	"gcd":        INT,
+	"lcm":        INT,
+	"sign":       INT,
+	"rand":       INT,
+	"srand":      VOID,
+	"fopen":      INT,
+	"fread_int":  INT,
+	"fwrite_int": VOID,
+	"feof":       BOOL,
+	"fclose":     VOID,
+}
+
+// Math builtins that take numbers and return an int, unless one
+// of their arguments is a double, e.g. max(1, 2.5) is a double
+var numericBuiltins = map[string]bool{
+	"abs":   true,
+	"min":   true,
+	"max":   true,
+	"pow":   true,
+	"clamp": true,
+}
+
+// Maps of operators to the types they can be applied to
+var arithmeticOperators = map[string]bool{"+": true, "-": true, "*": true, "/": true, "%": true}
+var comparisonOperators = map[string]bool{"<": true, ">": true, "<=": true, ">=": true}
+var equalityOperators = map[string]bool{"==": true, "!=": true}
+var logicalOperators = map[string]bool{"&&": true, "||": true}
+
+/*
+=======================
+Define the Checker type
+=======================
+*/
+
+// Checker walks the AST between parsing and evaluation and
+// rejects programs that mix values of different types
+type Checker struct {
+	// Type of each variable, taken from its declaration or the
+	// first value assigned to it
+	types map[string]string
+	// Struct definitions by name, used to find the types of fields
+	structs map[string]*ast.StructStatement
+	errors  []string
+}
+
+// CreateChecker creates a new Checker object
+func CreateChecker() *Checker {
+	return &Checker{
+		types:   make(map[string]string),
+		structs: make(map[string]*ast.StructStatement),
+		errors:  []string{},
+	}
+}
+
+// SetType records the type of a variable that is set before the program
+// is run, so that the program is checked against it
+func (checker *Checker) SetType(name, typeName string) {
+	checker.types[name] = integerType(typeName)
+}
+
+/*
+=====================
+Main checker function
+=====================
+*/
+
+// Check type checks every statement in the program, any problems
+// found can be retrieved with GetErrors
+func (checker *Checker) Check(program *ast.Program) {
+	checker.checkStatements(program.Statements)
+}
+
+/*
+==============================
+Error handling for type errors
+==============================
+*/
+
+// GetErrors will return any errors found while checking the program
+func (checker *Checker) GetErrors() []string {
+	return checker.errors
+}
+
+func (checker *Checker) logError(line, column int, format string, a ...interface{}) {
+	errorMsg := fmt.Sprintf("Type error at line %d, column %d: ", line, column)
+	checker.errors = append(checker.errors, errorMsg+fmt.Sprintf(format, a...))
+}
+
+/*
+===============
+Checker methods
+===============
+*/
+
+func (checker *Checker) checkStatements(statements []ast.Statement) {
+	for _, statement := range statements {
+		checker.checkStatement(statement)
+	}
+}
+
+func (checker *Checker) checkStatement(statement ast.Statement) {
+	switch node := statement.(type) {
+	case *ast.VariableStatement:
+		checker.checkVariableStatement(node)
+	case *ast.ExpressionStatement:
+		checker.checkExpression(node.Expression)
+	case *ast.StructStatement:
+		checker.structs[node.Name.Value] = node
+	case *ast.MemberAssignStatement:
+		checker.checkMemberAssignStatement(node)
+	case *ast.BlockStatement:
+		checker.checkStatements(node.Statements)
+	case *ast.IfStatement:
+		checker.checkCondition(node.Condition)
+		checker.checkStatement(node.FirstBranch)
+		if node.SecondBranch != nil {
+			checker.checkStatement(node.SecondBranch)
+		}
+	case *ast.WhileStatement:
+		checker.checkCondition(node.Condition)
+		checker.checkStatement(node.Loop)
+	case *ast.PrintStatement:
+		for _, value := range node.Values {
+			checker.checkValue(value)
+		}
+	case *ast.ReadStatement:
+		checker.checkReadStatement(node)
+	case *ast.AssertStatement:
+		checker.checkCondition(node.Condition)
+		if node.Message != nil {
+			messageType := checker.checkValue(node.Message)
+			if messageType != UNKNOWN && messageType != STRING {
+				line, column := position(node.Message)
+				checker.logError(line, column, "assert message must be a string, not %s", messageType)
+			}
+		}
+	}
+}
+
+// Only numbers can be read from input - a variable that hasn't been
+// assigned yet becomes an int
+func (checker *Checker) checkReadStatement(readStatement *ast.ReadStatement) {
+	for _, name := range readStatement.Names {
+		variableType := checker.types[name.Value]
+		if variableType == UNKNOWN {
+			checker.types[name.Value] = INT
+		} else if !numeric(variableType) {
+			checker.logError(name.Token.Line, name.Token.Column, "cannot read a %s value into %s", variableType, name.Value)
+		}
+	}
+}
+
+// Check a declaration or assignment - the value must match the declared
+// type, and a variable keeps the same type every time it is assigned
+func (checker *Checker) checkVariableStatement(varStatement *ast.VariableStatement) {
+	if varStatement == nil || varStatement.Name == nil {
+		return
+	}
+	name := varStatement.Name.Value
+	valueType := integerType(varStatement.Type)
+	if varStatement.Value != nil {
+		valueType = checker.checkValue(varStatement.Value)
+	}
+
+	expected := integerType(varStatement.Type)
+	if expected == UNKNOWN {
+		expected = checker.types[name]
+	}
+
+	if expected != UNKNOWN && valueType != UNKNOWN && !compatible(expected, valueType) {
+		tok := varStatement.Name.Token
+		checker.logError(tok.Line, tok.Column, "cannot assign %s value to %s variable %s", valueType, expected, name)
+		return
+	}
+	if expected == UNKNOWN {
+		expected = valueType
+	}
+	checker.types[name] = expected
+}
+
+// Check an assignment to a struct field - the value must match the field type
+func (checker *Checker) checkMemberAssignStatement(memberStatement *ast.MemberAssignStatement) {
+	fieldType := checker.checkExpression(memberStatement.Target)
+	valueType := checker.checkValue(memberStatement.Value)
+
+	if fieldType != UNKNOWN && valueType != UNKNOWN && !compatible(fieldType, valueType) {
+		tok := memberStatement.Target.Field.Token
+		checker.logError(tok.Line, tok.Column, "cannot assign %s value to %s field %s",
+			valueType, fieldType, memberStatement.Target.Field.Value)
+	}
+}
+
+// Conditions of if statements and while loops, and the operands of && and
+// ||, must be bools or numbers - like in C++ any non-zero number is true
+func (checker *Checker) checkCondition(condition ast.Expression) {
+	conditionType := checker.checkValue(condition)
+	if conditionType != UNKNOWN && conditionType != BOOL && !numeric(conditionType) {
+		line, column := position(condition)
+		checker.logError(line, column, "condition must be a bool or a number, not %s", conditionType)
+	}
+}
+
+// Work out the type of an expression, logging an error for any operator
+// that is applied to values of the wrong type
+func (checker *Checker) checkExpression(expression ast.Expression) string {
+	switch node := expression.(type) {
+	case *ast.Integer:
+		return INT
+	case *ast.Float:
+		return DOUBLE
+	case *ast.Boolean:
+		return BOOL
+	case *ast.String:
+		return STRING
+	case *ast.Identifier:
+		return checker.types[node.Value]
+	case *ast.InfixExpression:
+		if logicalOperators[node.Operator] {
+			checker.checkCondition(node.Left)
+			checker.checkCondition(node.Right)
+			return BOOL
+		}
+		left := checker.checkValue(node.Left)
+		right := checker.checkValue(node.Right)
+		return checker.checkInfix(node, left, right)
+	case *ast.MemberExpression:
+		return checker.checkMember(node)
+	case *ast.PrefixExpression:
+		return checker.checkPrefix(node)
+	case *ast.CallExpression:
+		return checker.checkCall(node)
+	default:
+		return UNKNOWN
+	}
+}
+
+// Work out the type of an expression whose value is used, e.g. one that
+// is printed, assigned or passed to a function. Builtins like srand()
+// return nothing, so calling them is only allowed as a statement
+func (checker *Checker) checkValue(expression ast.Expression) string {
+	valueType := checker.checkExpression(expression)
+	if valueType != VOID {
+		return valueType
+	}
+	line, column := position(expression)
+	if call, ok := expression.(*ast.CallExpression); ok {
+		checker.logError(line, column, "%s returns no value", call.Function.Value)
+	} else {
+		checker.logError(line, column, "void value cannot be used")
+	}
+	return UNKNOWN
+}
+
+// Calls must be to a builtin function, the function itself
+// checks the values of its arguments when it is called
+func (checker *Checker) checkCall(call *ast.CallExpression) string {
+	argumentTypes := []string{}
+	for _, argument := range call.Arguments {
+		argumentTypes = append(argumentTypes, checker.checkValue(argument))
+	}
+	if numericBuiltins[call.Function.Value] {
+		return checker.checkNumericCall(call, argumentTypes)
+	}
+	returnType, ok := builtinTypes[call.Function.Value]
+	if !ok {
+		tok := call.Token
+		checker.logError(tok.Line, tok.Column, "unknown function %s", call.Function.Value)
+		return UNKNOWN
+	}
+	return returnType
+}
+
+// The arguments of a numeric builtin must be numbers, and the
+// result is a double if any of them is a double
+func (checker *Checker) checkNumericCall(call *ast.CallExpression, argumentTypes []string) string {
+	returnType := INT
+	for i, argumentType := range argumentTypes {
+		switch {
+		case argumentType == UNKNOWN:
+			continue
+		case !numeric(argumentType):
+			line, column := position(call.Arguments[i])
+			checker.logError(line, column, "%s needs numbers, got %s", call.Function.Value, argumentType)
+			return UNKNOWN
+		case argumentType == DOUBLE:
+			returnType = DOUBLE
+		}
+	}
+	return returnType
+}
+
+// Prefix minus can only be applied to numbers
+func (checker *Checker) checkPrefix(prefix *ast.PrefixExpression) string {
+	operandType := checker.checkValue(prefix.Right)
+	if operandType != UNKNOWN && !numeric(operandType) {
+		tok := prefix.Token
+		checker.logError(tok.Line, tok.Column, "operator %s cannot be applied to %s", prefix.Operator, operandType)
+		return UNKNOWN
+	}
+	return operandType
+}
+
+// Get the type of a struct field, the object must be a struct with that field
+func (checker *Checker) checkMember(member *ast.MemberExpression) string {
+	objectType := checker.checkExpression(member.Object)
+	if objectType == UNKNOWN {
+		return UNKNOWN
+	}
+
+	tok := member.Field.Token
+	structDef, ok := checker.structs[objectType]
+	if !ok {
+		checker.logError(tok.Line, tok.Column, "cannot access field %s of %s", member.Field.Value, objectType)
+		return UNKNOWN
+	}
+	for _, field := range structDef.Fields {
+		if field.Name.Value == member.Field.Value {
+			return integerType(field.Type)
+		}
+	}
+	checker.logError(tok.Line, tok.Column, "%s has no field %s", objectType, member.Field.Value)
+	return UNKNOWN
+}
+
+func (checker *Checker) checkInfix(infix *ast.InfixExpression, left, right string) string {
+	// An int mixed with a double is promoted to a double
+	if numeric(left) && numeric(right) && left != right {
+		left, right = DOUBLE, DOUBLE
+	}
+	// Use whichever operand type is known so that a single unknown
+	// operand doesn't hide an error in the other
+	operandType := left
+	if operandType == UNKNOWN {
+		operandType = right
+	}
+
+	var allowed map[string]bool
+	result := operandType
+
+	switch {
+	case arithmeticOperators[infix.Operator]:
+		allowed = map[string]bool{INT: true, DOUBLE: true}
+		if infix.Operator == "+" {
+			allowed[STRING] = true
+		}
+		// Like C++, % only works on integers
+		if infix.Operator == "%" {
+			allowed[DOUBLE] = false
+		}
+	case comparisonOperators[infix.Operator]:
+		allowed = map[string]bool{INT: true, DOUBLE: true, STRING: true}
+		result = BOOL
+	case equalityOperators[infix.Operator]:
+		allowed = map[string]bool{INT: true, DOUBLE: true, STRING: true, BOOL: true}
+		result = BOOL
+	default:
+		return UNKNOWN
+	}
+
+	tok := infix.Token
+	if left != UNKNOWN && right != UNKNOWN && left != right {
+		checker.logError(tok.Line, tok.Column, "mismatched types %s %s %s", left, infix.Operator, right)
+		return UNKNOWN
+	}
+	if operandType != UNKNOWN && !allowed[operandType] {
+		checker.logError(tok.Line, tok.Column, "operator %s cannot be applied to %s", infix.Operator, operandType)
+		return UNKNOWN
+	}
+	return result
+}
+
+/*
+==============
+Helper methods
+==============
+*/
+
+// The fixed-width integer types can be mixed with each other and with
+// int, the evaluator converts between them, so they are all checked as int
+func integerType(typeName string) string {
+	if _, ok := symbol.IntegerKinds[typeName]; ok || typeName == "int64_t" {
+		return INT
+	}
+	return typeName
+}
+
+func numeric(typeName string) bool {
+	return typeName == INT || typeName == DOUBLE
+}
+
+// Values can be stored in a variable of the same type, and ints and
+// doubles are converted to each other when they are assigned
+func compatible(target, value string) bool {
+	return target == value || (numeric(target) && numeric(value))
+}
+
+// Get the position of the token that starts an expression
+func position(expression ast.Expression) (int, int) {
+	switch node := expression.(type) {
+	case *ast.Integer:
+		return node.Token.Line, node.Token.Column
+	case *ast.Float:
+		return node.Token.Line, node.Token.Column
+	case *ast.Boolean:
+		return node.Token.Line, node.Token.Column
+	case *ast.String:
+		return node.Token.Line, node.Token.Column
+	case *ast.Identifier:
+		return node.Token.Line, node.Token.Column
+	case *ast.InfixExpression:
+		return position(node.Left)
+	case *ast.MemberExpression:
+		return position(node.Object)
+	case *ast.PrefixExpression:
+		return node.Token.Line, node.Token.Column
+	case *ast.CallExpression:
+		return node.Token.Line, node.Token.Column
+	default:
+		return 0, 0
+	}
+}
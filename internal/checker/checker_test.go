@@ -0,0 +1,116 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sedexdev/go-interpreter/internal/lexer"
+	"github.com/sedexdev/go-interpreter/internal/parser"
+)
+
+// Parse and type check a program, returning the type errors found
+func checkProgram(t *testing.T, code string) []string {
+	t.Helper()
+	programParser := parser.CreateParser(lexer.CreateLexer(code))
+	program := programParser.ParseProgram()
+	if errors := programParser.GetErrors(); len(errors) > 0 {
+		t.Fatalf("program has syntax errors: %v", errors)
+	}
+	programChecker := CreateChecker()
+	programChecker.Check(program)
+	return programChecker.GetErrors()
+}
+
+func TestVoidValues(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"assigned", "int x = srand(1); print x;", "line 1, column 9: srand returns no value"},
+		{"assigned without a type", "x = fclose(1);", "line 1, column 5: fclose returns no value"},
+		{"printed", "print 1, srand(1);", "line 1, column 10: srand returns no value"},
+		{"left operand", "print exit(0) + 1;", "line 1, column 7: exit returns no value"},
+		{"right operand", "print 1 == fwrite_int(1, 2);", "line 1, column 12: fwrite_int returns no value"},
+		{"prefix operand", "print -srand(1);", "line 1, column 8: srand returns no value"},
+		{"argument", "print abs(srand(1));", "line 1, column 11: srand returns no value"},
+		{"condition", "if (srand(1)) { print 1; }", "line 1, column 5: srand returns no value"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errors := checkProgram(t, test.code)
+			if len(errors) != 1 || !strings.HasSuffix(errors[0], test.want) {
+				t.Fatalf("got errors %q, want one ending %q", errors, test.want)
+			}
+		})
+	}
+}
+
+func TestVoidCallStatements(t *testing.T) {
+	if errors := checkProgram(t, "srand(1); int h = fopen(\"a.txt\", \"w\"); fwrite_int(h, 1); fclose(h); exit(0);"); len(errors) > 0 {
+		t.Fatalf("unexpected errors: %q", errors)
+	}
+}
+
+func TestWellTypedPrograms(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"comparison as a condition", "int a = 1; int b = 2; if (a < b) { print 1; }"},
+		{"bool variable", "bool b = 1 < 2; b = false; print b == true;"},
+		{"int promoted to double", "int a = 1; double d = 2.5; print a + d, a < d, a == d;"},
+		{"ints and doubles assigned to each other", "int a = 1; double d = a; a = 2.5; d = 3;"},
+		{"fixed-width ints mixed", "int8_t a = 1; uint64_t b = 2; int c = a + b; a = 2.5;"},
+		{"integer remainder", "int a = 7; print a % 2;"},
+		{"string concatenation", `s = "a" + "b"; print s + "c", s < "b", s == "ab";`},
+		{"numbers as conditions", "int i = 3; while (i) { i = i - 1; } print 2 && 0.5;"},
+		{"struct fields", "struct P { int x; double y; }; P p; p.x = 1; p.y = 2; print p.x + p.y;"},
+		{"builtin results", "int n = printf(\"x\"); double m = max(1, 2.5); int k = abs(-3); bool e = eof();"},
+		{"variable used before it is assigned", "print x + 1;"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if errors := checkProgram(t, test.code); len(errors) > 0 {
+				t.Fatalf("unexpected errors: %q", errors)
+			}
+		})
+	}
+}
+
+func TestTypeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"comparison plus an int", "int a = 1; int b = 2; print (a < b) + 3;", "line 1, column 37: mismatched types bool + int"},
+		{"bool variable plus an int", "bool b = true; print b + 1;", "line 1, column 24: mismatched types bool + int"},
+		{"bool compared with an int", "print true == 1;", "line 1, column 12: mismatched types bool == int"},
+		{"bool assigned to an int", "int x = 1; x = true;", "line 1, column 12: cannot assign bool value to int variable x"},
+		{"int assigned to a bool", "bool b = 1;", "line 1, column 6: cannot assign int value to bool variable b"},
+		{"variable keeps its first type", "x = 1; x = \"s\";", "line 1, column 8: cannot assign string value to int variable x"},
+		{"remainder of doubles", "print 5.0 % 2.0;", "line 1, column 11: operator % cannot be applied to double"},
+		{"remainder of an int and a double", "print 5 % 2.0;", "line 1, column 9: operator % cannot be applied to double"},
+		{"string plus an int", `print "a" + 1;`, "line 1, column 11: mismatched types string + int"},
+		{"string minus a string", `print "a" - "b";`, "line 1, column 11: operator - cannot be applied to string"},
+		{"negated bool", "bool b = true; print -b;", "line 1, column 22: operator - cannot be applied to bool"},
+		{"string condition", `if ("s") { print 1; }`, "line 1, column 5: condition must be a bool or a number, not string"},
+		{"string operand of and", `print true && "s";`, "line 1, column 15: condition must be a bool or a number, not string"},
+		{"string assigned to a field", `struct P { int x; }; P p; p.x = "s";`, "line 1, column 29: cannot assign string value to int field x"},
+		{"unknown field", "struct P { int x; }; P p; print p.z;", "line 1, column 35: P has no field z"},
+		{"field of an int", "int a = 1; print a.x;", "line 1, column 20: cannot access field x of int"},
+		{"field type is used", "struct P { bool ok; }; P p; print p.ok + 1;", "line 1, column 40: mismatched types bool + int"},
+		{"unknown function", "print foo(1);", "line 1, column 7: unknown function foo"},
+		{"numeric builtin given a string", `print max(1, "a");`, "line 1, column 14: max needs numbers, got string"},
+		{"read into a string", `s = "a"; read s;`, "line 1, column 15: cannot read a string value into s"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errors := checkProgram(t, test.code)
+			if len(errors) != 1 || !strings.HasSuffix(errors[0], test.want) {
+				t.Fatalf("got errors %q, want one ending %q", errors, test.want)
+			}
+		})
+	}
+}
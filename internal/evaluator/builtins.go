@@ -0,0 +1,434 @@
+package evaluator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/sedexdev/go-interpreter/internal/symbol"
+)
+
+/*
+=================
+Builtin functions
+=================
+*/
+
+// builtinFunc is a function that C-- programs can call. It is
+// given the values of the arguments it was called with
+type builtinFunc func(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol
+
+// Map of builtin function names to their implementations
+var builtins = map[string]builtinFunc{
+	"printf":     builtinPrintf,
+	"input":      builtinInput,
+	"eof":        builtinEOF,
+	"exit":       builtinExit,
+	"argc":       builtinArgc,
+	"argi":       builtinArgi,
+	"env":        builtinEnv,
+	"abs":        builtinAbs,
+	"min":        builtinMin,
+	"max":        builtinMax,
+	"clamp":      builtinClamp,
+	"sign":       builtinSign,
+	"pow":        builtinPow,
+	"isqrt":      builtinIsqrt,
+	"gcd":        builtinGcd,
+	"lcm":        builtinLcm,
+	"rand":       builtinRand,
+	"srand":      builtinSrand,
+	"fopen":      builtinFopen,
+	"fread_int":  builtinFreadInt,
+	"fwrite_int": builtinFwriteInt,
+	"feof":       builtinFeof,
+	"fclose":     builtinFclose,
+}
+
+// printf writes its arguments using a C format string and returns
+// the number of bytes written, e.g. printf("%5d|%-3x|\n", n, m)
+func builtinPrintf(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if len(args) == 0 {
+		return raiseError("printf needs a format string")
+	}
+	format, ok := args[0].(*symbol.String)
+	if !ok {
+		return raiseError("printf format must be a string, got %s", typeName(args[0]))
+	}
+
+	output, err := formatPrintf(format.Value, args[1:])
+	if err != nil {
+		return err
+	}
+	if err := evaluator.write(output); err != nil {
+		return err
+	}
+	return &symbol.Integer{Value: int64(len(output))}
+}
+
+// input reads an int from input, e.g. x = input();
+func builtinInput(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if len(args) != 0 {
+		return raiseError("input takes no arguments, got %d", len(args))
+	}
+	return evaluator.readInteger(evaluator.input)
+}
+
+// eof is true once nothing but whitespace is left in the input, so a
+// program can read piped data with while (eof() == false) { read x; }
+func builtinEOF(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if len(args) != 0 {
+		return raiseError("eof takes no arguments, got %d", len(args))
+	}
+	err := skipInputSpace(evaluator.input)
+	if err == io.EOF {
+		return toBoolean(true)
+	}
+	if err != nil {
+		return raiseError("Couldn't read input: %v", err)
+	}
+	return toBoolean(false)
+}
+
+// exit stops the program straight away with the given exit status,
+// leaving any loops and blocks it is called from
+func builtinExit(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if len(args) != 1 {
+		return raiseError("exit takes 1 argument, got %d", len(args))
+	}
+	status, ok := args[0].(*symbol.Integer)
+	if !ok {
+		return raiseError("exit status must be an integer, got %s", typeName(args[0]))
+	}
+	code := status.Value
+	if status.Big != nil {
+		code = int64(new(big.Int).And(status.Big, uint64Mask).Uint64())
+	}
+	return &symbol.Error{Message: fmt.Sprintf("exit(%d)", code), Exit: true, ExitCode: int(code)}
+}
+
+// argc gives the number of arguments passed to the program,
+// not counting the name of the program itself
+func builtinArgc(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if len(args) != 0 {
+		return raiseError("argc takes no arguments, got %d", len(args))
+	}
+	return &symbol.Integer{Value: int64(len(evaluator.Args))}
+}
+
+// argi(k) gives the value of argument k as an int, counting from 0
+func builtinArgi(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if len(args) != 1 {
+		return raiseError("argi takes 1 argument, got %d", len(args))
+	}
+	index, ok := args[0].(*symbol.Integer)
+	if !ok {
+		return raiseError("argi index must be an integer, got %s", typeName(args[0]))
+	}
+	position := index.Value
+	if index.Big != nil {
+		position = -1
+		if index.Big.IsInt64() {
+			position = index.Big.Int64()
+		}
+	}
+	if position < 0 || position >= int64(len(evaluator.Args)) {
+		return raiseError("argi(%s) is out of range, the program has %d arguments", index.GetValue(), len(evaluator.Args))
+	}
+
+	argument := evaluator.Args[position]
+	if evaluator.BigIntegers {
+		if value, ok := new(big.Int).SetString(argument, 10); ok {
+			return &symbol.Integer{Big: value}
+		}
+	} else if value, err := strconv.ParseInt(argument, 10, 64); err == nil {
+		return &symbol.Integer{Value: value}
+	}
+	return raiseError("Argument %d is not an integer: %q", position, argument)
+}
+
+// env("NAME") gives the value of an environment variable, or an empty
+// string if it isn't set. Programs can read the environment but not change it
+func builtinEnv(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if len(args) != 1 {
+		return raiseError("env takes 1 argument, got %d", len(args))
+	}
+	name, ok := args[0].(*symbol.String)
+	if !ok {
+		return raiseError("env name must be a string, got %s", typeName(args[0]))
+	}
+	if evaluator.LookupEnv == nil {
+		return raiseError("Reading the environment is disabled")
+	}
+	value, _ := evaluator.LookupEnv(name.Value)
+	return &symbol.String{Value: value}
+}
+
+/*
+=============
+Reading input
+=============
+*/
+
+// Read an int from the next word of input
+func (evaluator *Evaluator) readInteger(input *bufio.Reader) symbol.Symbol {
+	word, err := readWord(input)
+	if err != nil {
+		return err
+	}
+	if evaluator.BigIntegers {
+		value, ok := new(big.Int).SetString(word, 10)
+		if !ok {
+			return raiseError("Expected an integer in input, got %q", word)
+		}
+		return &symbol.Integer{Big: value}
+	}
+	value, parseErr := strconv.ParseInt(word, 10, 64)
+	if errors.Is(parseErr, strconv.ErrRange) {
+		return raiseError("Integer %s in input is too large for an int", word)
+	}
+	if parseErr != nil {
+		return raiseError("Expected an integer in input, got %q", word)
+	}
+	return &symbol.Integer{Value: value}
+}
+
+// Read a double from the next word of input
+func readFloat(input *bufio.Reader) symbol.Symbol {
+	word, err := readWord(input)
+	if err != nil {
+		return err
+	}
+	value, parseErr := strconv.ParseFloat(word, 64)
+	if parseErr != nil && !errors.Is(parseErr, strconv.ErrRange) {
+		return raiseError("Expected a number in input, got %q", word)
+	}
+	return &symbol.Float{Value: value}
+}
+
+// Read the next word of input, skipping the whitespace before it like
+// cin >> does. Running out of input is an error, eof() can be used to
+// check whether there is anything left first
+func readWord(input *bufio.Reader) (string, *symbol.Error) {
+	if err := skipInputSpace(input); err == io.EOF {
+		return "", raiseError("Unexpected end of input")
+	} else if err != nil {
+		return "", raiseError("Couldn't read input: %v", err)
+	}
+
+	var word []byte
+	for {
+		char, err := input.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", raiseError("Couldn't read input: %v", err)
+		}
+		if inputSpace(char) {
+			input.UnreadByte()
+			break
+		}
+		word = append(word, char)
+	}
+	return string(word), nil
+}
+
+// Skip whitespace in the input, returning io.EOF if there is nothing after it
+func skipInputSpace(input *bufio.Reader) error {
+	for {
+		char, err := input.ReadByte()
+		if err != nil {
+			return err
+		}
+		if !inputSpace(char) {
+			return input.UnreadByte()
+		}
+	}
+}
+
+func inputSpace(char byte) bool {
+	return char == ' ' || char == '\t' || char == '\n' || char == '\r' || char == '\v' || char == '\f'
+}
+
+/*
+========================
+Formatting printf output
+========================
+*/
+
+// Format values the way C's printf does. Each conversion can have the flags
+// "-+ 0#", a width and a precision. The conversions are d and i for signed
+// integers, u, x, X, o and b for unsigned integers, c for characters, s for
+// any value and f, e and g for doubles. Length modifiers such as the l in
+// %ld are accepted but make no difference, as every int has 64 bits
+func formatPrintf(format string, args []symbol.Symbol) (string, *symbol.Error) {
+	var output strings.Builder
+	next := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			output.WriteByte(format[i])
+			continue
+		}
+
+		// Read the flags, width and precision, which mean the same
+		// to Go's fmt package as they do to printf
+		start := i
+		i++
+		for i < len(format) && strings.IndexByte("-+ 0#", format[i]) >= 0 {
+			i++
+		}
+		for i < len(format) && digit(format[i]) {
+			i++
+		}
+		if i < len(format) && format[i] == '.' {
+			i++
+			for i < len(format) && digit(format[i]) {
+				i++
+			}
+		}
+		spec := format[start:i]
+		for i < len(format) && strings.IndexByte("hlLqjzt", format[i]) >= 0 {
+			i++
+		}
+
+		if i >= len(format) {
+			return "", raiseError("printf format %q ends part way through a conversion", format)
+		}
+		if format[i] == '%' {
+			output.WriteByte('%')
+			continue
+		}
+		if next >= len(args) {
+			return "", raiseError("printf format %q needs more than %d %s", format, len(args), plural(len(args), "argument"))
+		}
+
+		text, err := formatConversion(spec, format[i], args[next])
+		if err != nil {
+			return "", err
+		}
+		output.WriteString(text)
+		next++
+	}
+	return output.String(), nil
+}
+
+// Format a single value for a conversion such as %08.3f
+func formatConversion(spec string, conversion byte, value symbol.Symbol) (string, *symbol.Error) {
+	switch conversion {
+	case 'd', 'i':
+		integer, err := printfInteger(value, conversion, false)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(spec+"d", integer), nil
+	case 'u', 'x', 'X', 'o', 'b':
+		integer, err := printfInteger(value, conversion, true)
+		if err != nil {
+			return "", err
+		}
+		verb := string(conversion)
+		if conversion == 'u' {
+			verb = "d"
+		}
+		return fmt.Sprintf(spec+verb, integer), nil
+	case 'c':
+		integer, ok := value.(*symbol.Integer)
+		if !ok {
+			return "", raiseError("printf %%c expects an integer, got %s", typeName(value))
+		}
+		// The character is the low byte of the value, as in C
+		code := integer.Value
+		if integer.Big != nil {
+			code = int64(new(big.Int).And(integer.Big, uint64Mask).Uint64())
+		}
+		return fmt.Sprintf(textSpec(spec), string([]byte{byte(code)})), nil
+	case 'f', 'F', 'e', 'E', 'g', 'G':
+		float, ok := value.(*symbol.Float)
+		if !ok {
+			return "", raiseError("printf %%%c expects a double, got %s", conversion, typeName(value))
+		}
+		if math.IsInf(float.Value, 0) || math.IsNaN(float.Value) {
+			// Go writes +Inf and NaN, C writes inf and nan
+			text := float.GetValue()
+			if conversion >= 'A' && conversion <= 'Z' {
+				text = strings.ToUpper(text)
+			}
+			if strings.Contains(spec, "+") && !math.Signbit(float.Value) {
+				text = "+" + text
+			}
+			return fmt.Sprintf(textSpec(spec), text), nil
+		}
+		// Without a precision C shows 6 significant digits for %g,
+		// where Go would show as many as it takes to be exact
+		if (conversion == 'g' || conversion == 'G') && !strings.Contains(spec, ".") {
+			spec += ".6"
+		}
+		return fmt.Sprintf(spec+string(conversion), float.Value), nil
+	case 's':
+		return fmt.Sprintf(spec+"s", value.GetValue()), nil
+	default:
+		return "", raiseError("printf conversion %%%c is not supported", conversion)
+	}
+}
+
+// Get the value of an integer argument in a form fmt can format. Bools are
+// promoted to 0 or 1 as they are in C++. Unsigned conversions read the bits
+// of the value as unsigned - types narrower than int are promoted to 32 bits
+// first, so printf("%x", (int8_t)-1) gives ffffffff like it does in C++
+func printfInteger(value symbol.Symbol, conversion byte, unsigned bool) (interface{}, *symbol.Error) {
+	switch number := value.(type) {
+	case *symbol.Boolean:
+		if number.Value {
+			return 1, nil
+		}
+		return 0, nil
+	case *symbol.Integer:
+		switch {
+		case number.Big != nil && unsigned && number.Big.Sign() < 0:
+			// A negative big int is read as a 64 bit int, the same
+			// as it would be without big integers
+			if !number.Big.IsInt64() {
+				return nil, raiseError("printf %%%c of %s, which is too large to be read as unsigned", conversion, number.Big)
+			}
+			return uint64(number.Big.Int64()), nil
+		case number.Big != nil:
+			return number.Big, nil
+		case number.Kind != nil && number.Kind.Unsigned && number.Kind.Bits == 64:
+			return uint64(number.Value), nil
+		case !unsigned:
+			return number.Value, nil
+		case number.Kind != nil && number.Kind.Bits <= 32:
+			return uint64(uint32(number.Value)), nil
+		default:
+			return uint64(number.Value), nil
+		}
+	default:
+		return nil, raiseError("printf %%%c expects an integer, got %s", conversion, typeName(value))
+	}
+}
+
+// Turn a conversion spec into one for text that is written as it is, such
+// as a character or inf. Only the width and alignment are kept - the
+// precision would cut the text short and the other flags are for numbers
+func textSpec(spec string) string {
+	if point := strings.IndexByte(spec, '.'); point >= 0 {
+		spec = spec[:point]
+	}
+	width := strings.TrimLeft(spec[1:], "-+ 0#")
+	if strings.Contains(spec[1:len(spec)-len(width)], "-") {
+		return "%-" + width + "s"
+	}
+	return "%" + width + "s"
+}
+
+// Match a decimal digit
+func digit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
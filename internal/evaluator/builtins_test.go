@@ -0,0 +1,99 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/sedexdev/go-interpreter/internal/symbol"
+)
+
+// The expected output of each case is what C's printf gives for the same
+// format and values, with an int being 64 bits wide as it is in C--
+func TestPrintf(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"percent sign", `printf("100%%\n");`, "100%\n"},
+		{"signed", `printf("%d %i %+d|% d\n", -42, 7, 5, 5);`, "-42 7 +5| 5\n"},
+		{"width and padding", `printf("%5d|%-5d|%05d\n", 42, 42, -42);`, "   42|42   |-0042\n"},
+		{"alternate hex", `printf("%#x %#X %#o\n", 255, 255, 8);`, "0xff 0XFF 010\n"},
+		{"zero padded binary", `printf("%08b\n", 5);`, "00000101\n"},
+		{"left aligned string", `printf("%-8s|%5s|%.2s\n", "ab", "x", "hello");`, "ab      |    x|he\n"},
+		{"bools are ints", `printf("%d %d\n", true, false);`, "1 0\n"},
+		{"doubles", `printf("%f %.2f %5.1f %e\n", 1.5, 3.14159, 3.14159, 12345.678);`, "1.500000 3.14   3.1 1.234568e+04\n"},
+		{"six significant digits", `printf("%g %g %G %.3g %g\n", 1.0 / 3.0, 1000000.0, 0.00001, 3.14159, 100.0);`, "0.333333 1e+06 1E-05 3.14 100\n"},
+		{"infinity", `printf("%+f %f %F|%6f|\n", 1.0 / 0.0, -1.0 / 0.0, 1.0 / 0.0, 1.0 / 0.0);`, "+inf -inf INF|   inf|\n"},
+		{"characters", `printf("%c%c|%3c|%-3c|\n", 72, 105, 66, 67);`, "Hi|  B|C  |\n"},
+		{"character of the low byte", `printf("%c\n", 321);`, "A\n"},
+
+		// Unsigned conversions read the bits of the value, after
+		// promoting types narrower than int to 32 bits
+		{"hex of narrow negative", `int8_t a = -1; printf("%x\n", a);`, "ffffffff\n"},
+		{"hex of narrow negative upper case", `int16_t a = -2; printf("%X\n", a);`, "FFFFFFFE\n"},
+		{"unsigned of narrow negative", `int8_t a = -1; printf("%u\n", a);`, "4294967295\n"},
+		{"hex of unsigned narrow", `uint8_t a = 255; printf("%x %u\n", a, a);`, "ff 255\n"},
+		{"hex of negative int", `printf("%x\n", -1);`, "ffffffffffffffff\n"},
+		{"unsigned of negative int", `printf("%u\n", -1);`, "18446744073709551615\n"},
+		{"uint64_t", `uint64_t a = -1; printf("%u %d\n", a, a);`, "18446744073709551615 18446744073709551615\n"},
+		{"int32_t", `int32_t a = -5; printf("%d %o\n", a, a);`, "-5 37777777773\n"},
+
+		{"result is the number of bytes", `int n = printf("abc\n"); print n;`, "abc\n4\n"},
+		{"length modifiers", `printf("%ld %lld %hhx %zu\n", 1, 2, 255, 3);`, "1 2 ff 3\n"},
+	}
+	for mode, configure := range integerModes {
+		for _, test := range tests {
+			t.Run(mode+"/"+test.name, func(t *testing.T) {
+				output, result := runProgram(t, test.code, configure)
+				if err, ok := result.(*symbol.Error); ok {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if output != test.want {
+					t.Errorf("printed %q, want %q", output, test.want)
+				}
+			})
+		}
+	}
+}
+
+func TestPrintfBigIntegers(t *testing.T) {
+	bigIntegers := func(evaluator *Evaluator) { evaluator.BigIntegers = true }
+	output, result := runProgram(t, `printf("%d %x %c\n", pow(2, 70), pow(2, 70), pow(2, 70) + 65);`, bigIntegers)
+	if err, ok := result.(*symbol.Error); ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "1180591620717411303424 400000000000000000 A\n"; output != want {
+		t.Errorf("printed %q, want %q", output, want)
+	}
+
+	_, result = runProgram(t, `printf("%u\n", -pow(2, 70));`, bigIntegers)
+	if _, ok := result.(*symbol.Error); !ok {
+		t.Fatalf("got %v, want an error for a negative value too large to be unsigned", result)
+	}
+}
+
+func TestPrintfErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"no format", `printf();`, "printf needs a format string"},
+		{"format isn't a string", `printf(1);`, "printf format must be a string, got INTEGER"},
+		{"too few arguments", `printf("%d %d\n", 1);`, `printf format "%d %d\n" needs more than 1 argument`},
+		{"no arguments", `printf("%s\n");`, `printf format "%s\n" needs more than 0 arguments`},
+		{"unfinished conversion", `printf("%5");`, `printf format "%5" ends part way through a conversion`},
+		{"unsupported conversion", `printf("%y", 1);`, "printf conversion %y is not supported"},
+		{"int conversion of a double", `printf("%d", 1.5);`, "printf %d expects an integer, got FLOAT"},
+		{"double conversion of an int", `printf("%f", 1);`, "printf %f expects a double, got INTEGER"},
+		{"character of a string", `printf("%c", "a");`, "printf %c expects an integer, got STRING"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, result := runProgram(t, test.code, nil)
+			if err, ok := result.(*symbol.Error); !ok || err.Message != test.want {
+				t.Fatalf("got %v, want error %q", result, test.want)
+			}
+		})
+	}
+}
@@ -1,153 +1,1114 @@
 package evaluator
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/sedexdev/go-interpreter/internal/ast"
 	"github.com/sedexdev/go-interpreter/internal/symbol"
 )
 
+/*
+=========================
+Define the Evaluator type
+=========================
+*/
+
+// Evaluator holds the symbol table a program is evaluated against
+// along with the options that control how it is evaluated
+type Evaluator struct {
+	symbolTable *symbol.SymbolTable
+	// Output is where print statements write to
+	Output io.Writer
+	// Input that read statements and input() read from
+	input *bufio.Reader
+	// Args are the arguments passed to the program, read with argc()
+	// and argi(k). LookupEnv is used by env("NAME") to read environment
+	// variables - when it is nil programs can't read the environment
+	Args      []string
+	LookupEnv func(name string) (string, bool)
+	// FileRoot is the directory programs can open files in with fopen(),
+	// paths are relative to it and can't leave it. File access is
+	// disabled when it is empty
+	FileRoot string
+	// CheckedArithmetic makes signed integer overflow a runtime error
+	// instead of wrapping around
+	CheckedArithmetic bool
+	// BigIntegers backs integers with math/big so they never overflow
+	BigIntegers bool
+	// DisableAssertions skips assert statements without evaluating
+	// their conditions, like compiling C++ with NDEBUG
+	DisableAssertions bool
+	// MaxSteps stops a program once it has evaluated this many statements
+	// and loop iterations, zero means there is no limit
+	MaxSteps int
+	// Timeout stops a program started with Run once it has been running
+	// for this long, zero means there is no limit
+	Timeout time.Duration
+	// MaxOutput limits the number of bytes a program can print or write
+	// to files and MaxDepth limits how deeply nodes can be nested, zero
+	// means there is no limit. Programs from the parser are already
+	// limited by its maximum depth, MaxDepth guards ASTs that are built
+	// directly
+	MaxOutput int
+	MaxDepth  int
+	// Random number generator used by rand(), reseeded by srand()
+	random *rand.Rand
+	// Files opened by the program, keyed by the handles fopen() returned
+	files      map[int64]*openFile
+	nextHandle int64
+	// Context of the program started with Run and how far it has got -
+	// the steps it has taken, the bytes it has printed and the depth of
+	// the node being evaluated
+	ctx         context.Context
+	steps       int
+	outputBytes int
+	depth       int
+}
+
+// CreateEvaluator creates a new Evaluator object
+func CreateEvaluator(symbolTable *symbol.SymbolTable) *Evaluator {
+	return &Evaluator{
+		symbolTable: symbolTable,
+		Output:      os.Stdout,
+		input:       bufio.NewReader(os.Stdin),
+		LookupEnv:   os.LookupEnv,
+		random:      createRandom(DEFAULTSEED),
+	}
+}
+
+// SetInput sets the reader that read statements and input() read from.
+// Passing the same *bufio.Reader to several evaluators lets them share
+// input without losing anything that one of them has buffered
+func (evaluator *Evaluator) SetInput(input io.Reader) {
+	evaluator.input = bufio.NewReader(input)
+}
+
 /*
 ========================
 Main Evaluation Function
 ========================
 */
 
+// Run evaluates a program under the evaluator's execution limits. The
+// program is stopped with a limit exceeded error if it takes too many
+// steps, runs for longer than the timeout or ctx is cancelled
+func (evaluator *Evaluator) Run(ctx context.Context, program ast.Node) symbol.Symbol {
+	if evaluator.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, evaluator.Timeout)
+		defer cancel()
+	}
+	evaluator.ctx = ctx
+	evaluator.steps = 0
+	evaluator.outputBytes = 0
+	defer func() { evaluator.ctx = nil }()
+
+	// Files the program leaves open are closed when it finishes, and
+	// data that couldn't be written to them is reported as an error
+	result := evaluator.Evaluate(program)
+	if err := evaluator.closeFiles(); err != nil && !isError(result) {
+		return err
+	}
+	return result
+}
+
 // Evaluate walks the AST and evaluates each node. This function recursively
 // go through each node and evaluates each statement until it arrives at a
-// concrete value
-func Evaluate(program ast.Node, symbolTable *symbol.SymbolTable) symbol.Symbol {
+// concrete value. If a runtime error occurs evaluation stops and the error
+// is returned, located at the innermost node that caused it
+func (evaluator *Evaluator) Evaluate(program ast.Node) symbol.Symbol {
+	evaluator.depth++
+	defer func() { evaluator.depth-- }()
+
+	var result symbol.Symbol
+	if evaluator.MaxDepth > 0 && evaluator.depth > evaluator.MaxDepth {
+		result = limitExceeded("nesting is deeper than the limit of %d", evaluator.MaxDepth)
+	} else {
+		result = evaluator.evaluateNode(program)
+	}
+	if err, ok := result.(*symbol.Error); ok && err.Line == 0 {
+		tok := program.GetToken()
+		err.Line = tok.Line
+		err.Column = tok.Column
+	}
+	return result
+}
+
+func (evaluator *Evaluator) evaluateNode(program ast.Node) symbol.Symbol {
 	switch node := program.(type) {
 	case *ast.Program:
-		return evaluateStatements(node.Statements, symbolTable)
+		return evaluator.evaluateStatements(node.Statements)
 	case *ast.VariableStatement:
-		variableValue := Evaluate(node.Value, symbolTable)
-		symbolTable.Set(node.Name.Value, variableValue)
-		return variableValue
+		return evaluator.evaluateVariableStatement(node)
 	case *ast.ExpressionStatement:
-		return Evaluate(node.Expression, symbolTable)
+		return evaluator.Evaluate(node.Expression)
+	case *ast.StructStatement:
+		// Struct definitions are resolved by the parser, so there
+		// is nothing to do when they are reached
+		return &symbol.Dummy{Value: ""}
+	case *ast.MemberAssignStatement:
+		return evaluator.evaluateMemberAssignStatement(node)
 	case *ast.BlockStatement:
-		return evaluateStatements(node.Statements, symbolTable)
+		return evaluator.evaluateStatements(node.Statements)
 	case *ast.IfStatement:
-		return evaluateIfStatement(node, symbolTable)
+		return evaluator.evaluateIfStatement(node)
 	case *ast.WhileStatement:
-		return evaluateWhileStatement(node, symbolTable)
+		return evaluator.evaluateWhileStatement(node)
 	case *ast.PrintStatement:
-		return evaluatePrintStatement(node, symbolTable)
+		return evaluator.evaluatePrintStatement(node)
+	case *ast.ReadStatement:
+		return evaluator.evaluateReadStatement(node)
+	case *ast.AssertStatement:
+		return evaluator.evaluateAssertStatement(node)
 	case *ast.InfixExpression:
-		left := Evaluate(node.Left, symbolTable)
-		right := Evaluate(node.Right, symbolTable)
-		return evaluateInfix(node.Operator, left, right)
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evaluator.evaluateLogical(node)
+		}
+		left := evaluator.Evaluate(node.Left)
+		if isError(left) {
+			return left
+		}
+		right := evaluator.Evaluate(node.Right)
+		if isError(right) {
+			return right
+		}
+		return evaluator.evaluateInfix(node.Operator, left, right)
+	case *ast.PrefixExpression:
+		right := evaluator.Evaluate(node.Right)
+		if isError(right) {
+			return right
+		}
+		return evaluator.evaluatePrefix(node.Operator, right)
+	case *ast.MemberExpression:
+		return evaluator.evaluateMemberExpression(node)
+	case *ast.CallExpression:
+		return evaluator.evaluateCall(node)
 	case *ast.Identifier:
-		return evaluateIdentifier(node, symbolTable)
+		return evaluator.evaluateIdentifier(node)
 	case *ast.Integer:
-		return &symbol.Integer{Value: node.Value}
+		return evaluator.evaluateInteger(node)
+	case *ast.Float:
+		return &symbol.Float{Value: node.Value}
+	case *ast.String:
+		return &symbol.String{Value: node.Value}
+	case *ast.Boolean:
+		return toBoolean(node.Value)
 	default:
 		return nil
 	}
 }
 
+// Map of declared type names to the symbol type their values must have
+var declaredTypes = map[string]string{
+	"int":      "INTEGER",
+	"int8_t":   "INTEGER",
+	"int16_t":  "INTEGER",
+	"int32_t":  "INTEGER",
+	"int64_t":  "INTEGER",
+	"uint8_t":  "INTEGER",
+	"uint16_t": "INTEGER",
+	"uint32_t": "INTEGER",
+	"uint64_t": "INTEGER",
+	"double":   "FLOAT",
+	"bool":     "BOOLEAN",
+}
+
 /*
 ================
 Error generation
 ================
 */
 
+// Errors are created without a location, Evaluate gives them the position
+// of the node being evaluated when they are returned
 func raiseError(format string, a ...interface{}) *symbol.Error {
 	return &symbol.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+func isError(value symbol.Symbol) bool {
+	_, ok := value.(*symbol.Error)
+	return ok
+}
+
+// Record a statement that an error passed through on its way out of the
+// program, building up a trace of what was being evaluated
+func addTraceFrame(err *symbol.Error, statement ast.Statement) {
+	tok := statement.GetToken()
+	frame := fmt.Sprintf("in %s at line %d, column %d", describeStatement(statement), tok.Line, tok.Column)
+	err.Trace = append(err.Trace, frame)
+}
+
+func describeStatement(statement ast.Statement) string {
+	switch node := statement.(type) {
+	case *ast.VariableStatement:
+		if node.Type != "" {
+			return "declaration of " + node.Name.Value
+		}
+		return "assignment to " + node.Name.Value
+	case *ast.MemberAssignStatement:
+		return "assignment to field " + node.Target.Field.Value
+	case *ast.IfStatement:
+		return "if statement"
+	case *ast.WhileStatement:
+		return "while loop"
+	case *ast.PrintStatement:
+		return "print statement"
+	case *ast.ReadStatement:
+		return "read statement"
+	case *ast.AssertStatement:
+		return "assert statement"
+	default:
+		return "statement"
+	}
+}
+
+/*
+================
+Execution limits
+================
+*/
+
+// Count a step of the program and check that it is still within its
+// limits, returning a limit exceeded error if it isn't
+func (evaluator *Evaluator) takeStep() *symbol.Error {
+	evaluator.steps++
+	if evaluator.MaxSteps > 0 && evaluator.steps > evaluator.MaxSteps {
+		return limitExceeded("the program took more than %d %s", evaluator.MaxSteps, plural(evaluator.MaxSteps, "step"))
+	}
+	if evaluator.ctx == nil {
+		return nil
+	}
+	switch evaluator.ctx.Err() {
+	case context.DeadlineExceeded:
+		if evaluator.Timeout > 0 {
+			return limitExceeded("the program ran for longer than %s", evaluator.Timeout)
+		}
+		return limitExceeded("the program passed its deadline")
+	case context.Canceled:
+		return limitExceeded("the program was cancelled")
+	}
+	return nil
+}
+
+func limitExceeded(format string, a ...interface{}) *symbol.Error {
+	err := raiseError(format, a...)
+	err.LimitExceeded = true
+	return err
+}
+
 /*
 ====================================================
 Helper functions for evaluating different statements
 ====================================================
 */
 
-func evaluateStatements(statements []ast.Statement, symbolTable *symbol.SymbolTable) symbol.Symbol {
+func (evaluator *Evaluator) evaluateStatements(statements []ast.Statement) symbol.Symbol {
 	var result symbol.Symbol
-	// Loop over each statement in the statements array
+	// Loop over each statement in the statements array, stopping
+	// at the first statement that raises an error
 	for _, statement := range statements {
-		result = Evaluate(statement, symbolTable)
+		if err := evaluator.takeStep(); err != nil {
+			tok := statement.GetToken()
+			err.Line, err.Column = tok.Line, tok.Column
+			addTraceFrame(err, statement)
+			return err
+		}
+		result = evaluator.Evaluate(statement)
+		if err, ok := result.(*symbol.Error); ok {
+			addTraceFrame(err, statement)
+			return err
+		}
 	}
 	return result
 }
 
-func evaluateIdentifier(node *ast.Identifier, symbolTable *symbol.SymbolTable) symbol.Symbol {
+func (evaluator *Evaluator) evaluateVariableStatement(varStatement *ast.VariableStatement) symbol.Symbol {
+	name := varStatement.Name.Value
+	// A constant declaration that is reached again, e.g. inside a loop,
+	// evaluates its initialiser again like it would in a C++ block. Only
+	// plain assignments to a constant are refused
+	if evaluator.symbolTable.IsConstant(name) && !varStatement.Constant {
+		return raiseError("Cannot assign to constant: %s", name)
+	}
+
+	var variableValue symbol.Symbol
+	if varStatement.Value == nil {
+		variableValue = zeroValue(varStatement.Type, varStatement.Struct)
+	} else {
+		variableValue = copyValue(evaluator.Evaluate(varStatement.Value))
+	}
+	// Numbers are converted to the type of the variable, which truncates
+	// doubles stored in ints and values stored in the fixed-width types
+	variableValue = evaluator.convertNumber(variableValue, evaluator.variableTarget(varStatement))
+	if isError(variableValue) {
+		return variableValue
+	}
+
+	if expected, ok := declaredTypes[varStatement.Type]; ok && variableValue.GetType() != expected {
+		return raiseError("Cannot initialise %s %s with a %s value", varStatement.Type, name, variableValue.GetType())
+	}
+	if varStatement.Struct != nil && !sameType(variableValue, zeroValue(varStatement.Type, varStatement.Struct)) {
+		return raiseError("Cannot initialise %s %s with a %s value", varStatement.Type, name, typeName(variableValue))
+	}
+
+	if varStatement.Constant {
+		return evaluator.symbolTable.SetConstant(name, variableValue)
+	}
+	return evaluator.symbolTable.Set(name, variableValue)
+}
+
+func (evaluator *Evaluator) evaluateMemberAssignStatement(memberStatement *ast.MemberAssignStatement) symbol.Symbol {
+	if root := rootIdentifier(memberStatement.Target); root != nil && evaluator.symbolTable.IsConstant(root.Value) {
+		return raiseError("Cannot assign to constant: %s", root.Value)
+	}
+
+	// Evaluating the object gives the struct stored in the symbol table
+	// rather than a copy, so the field can be updated in place
+	object := evaluator.Evaluate(memberStatement.Target.Object)
+	if isError(object) {
+		return object
+	}
+	structure, ok := object.(*symbol.Struct)
+	if !ok {
+		return raiseError("Cannot access field %s of a %s value", memberStatement.Target.Field.Value, typeName(object))
+	}
+
+	field := memberStatement.Target.Field.Value
+	current, ok := structure.Fields[field]
+	if !ok {
+		return raiseError("%s has no field %s", structure.TypeName, field)
+	}
+
+	value := copyValue(evaluator.Evaluate(memberStatement.Value))
+	if !isError(value) {
+		value = evaluator.convertNumber(value, current)
+	}
+	if isError(value) {
+		return value
+	}
+	if !sameType(current, value) {
+		return raiseError("Cannot assign %s value to %s field %s", typeName(value), typeName(current), field)
+	}
+	structure.Fields[field] = value
+	return value
+}
+
+func (evaluator *Evaluator) evaluateMemberExpression(member *ast.MemberExpression) symbol.Symbol {
+	object := evaluator.Evaluate(member.Object)
+	if isError(object) {
+		return object
+	}
+	structure, ok := object.(*symbol.Struct)
+	if !ok {
+		return raiseError("Cannot access field %s of a %s value", member.Field.Value, typeName(object))
+	}
+	value, ok := structure.Fields[member.Field.Value]
+	if !ok {
+		return raiseError("%s has no field %s", structure.TypeName, member.Field.Value)
+	}
+	return value
+}
+
+func (evaluator *Evaluator) evaluateIdentifier(node *ast.Identifier) symbol.Symbol {
 	// Check the symbol table to see if the identifier exists
-	variableValue, ok := symbolTable.Get(node.Value)
+	variableValue, ok := evaluator.symbolTable.Get(node.Value)
 	if !ok {
-		return raiseError("Couldn't find identifier: " + node.Value)
+		return raiseError("Couldn't find identifier: %s", node.Value)
 	}
 	return variableValue
 }
 
-func evaluateIfStatement(ifStatement *ast.IfStatement, symbolTable *symbol.SymbolTable) symbol.Symbol {
-	// Evaluate the condition to digit (1 or 0) and then progress down the
+func (evaluator *Evaluator) evaluateIfStatement(ifStatement *ast.IfStatement) symbol.Symbol {
+	// Evaluate the condition to a bool and then progress down the
 	// appropriate branch based on the result
-	condition := Evaluate(ifStatement.Condition, symbolTable)
-	if condition.GetValue() == "1" {
-		return Evaluate(ifStatement.FirstBranch, symbolTable)
-	} else if condition.GetValue() == "0" && ifStatement.SecondBranch != nil {
-		return Evaluate(ifStatement.SecondBranch, symbolTable)
+	result, err := evaluator.evaluateCondition(ifStatement.Condition)
+	if err != nil {
+		return err
+	}
+	if result {
+		return evaluator.Evaluate(ifStatement.FirstBranch)
+	} else if ifStatement.SecondBranch != nil {
+		return evaluator.Evaluate(ifStatement.SecondBranch)
 	}
 	// Dummy return means nothing is printed to the console when the function
 	// has nothing to return
 	return &symbol.Dummy{Value: ""}
 }
 
-func evaluateWhileStatement(whileStatement *ast.WhileStatement, symbolTable *symbol.SymbolTable) symbol.Symbol {
+func (evaluator *Evaluator) evaluateWhileStatement(whileStatement *ast.WhileStatement) symbol.Symbol {
 	// Keep checking the condition to make sure it is still true
-	for Evaluate(whileStatement.Condition, symbolTable).GetValue() == "1" {
-		Evaluate(whileStatement.Loop, symbolTable)
+	for {
+		// Each iteration is a step, so that even a loop with an
+		// empty body can be stopped
+		if err := evaluator.takeStep(); err != nil {
+			return err
+		}
+		result, err := evaluator.evaluateCondition(whileStatement.Condition)
+		if err != nil {
+			return err
+		}
+		if !result {
+			break
+		}
+		if result := evaluator.Evaluate(whileStatement.Loop); isError(result) {
+			return result
+		}
 	}
 	// Return the Dummy type when the loop has completed
 	return &symbol.Dummy{Value: ""}
 }
 
-func evaluatePrintStatement(printStatement *ast.PrintStatement, symbolTable *symbol.SymbolTable) symbol.Symbol {
+// Values are printed separated by single spaces, followed by a newline
+// unless the statement is a print_inline
+func (evaluator *Evaluator) evaluatePrintStatement(printStatement *ast.PrintStatement) symbol.Symbol {
+	values := []string{}
 	for _, expression := range printStatement.Values {
-		fmt.Print(Evaluate(expression, symbolTable).GetValue() + " ")
+		value := evaluator.Evaluate(expression)
+		if isError(value) {
+			return value
+		}
+		values = append(values, value.GetValue())
+	}
+
+	output := strings.Join(values, " ")
+	if printStatement.Newline {
+		output += "\n"
+	}
+	if err := evaluator.write(output); err != nil {
+		return err
 	}
 	return &symbol.Dummy{Value: ""}
 }
 
-func evaluateInfix(operator string, left, right symbol.Symbol) symbol.Symbol {
-	leftValue := left.(*symbol.Integer).Value
-	rightValue := right.(*symbol.Integer).Value
+// Read a value from input into each variable in turn. Variables that hold a
+// double read a double, anything else reads an int
+func (evaluator *Evaluator) evaluateReadStatement(readStatement *ast.ReadStatement) symbol.Symbol {
+	for _, name := range readStatement.Names {
+		if evaluator.symbolTable.IsConstant(name.Value) {
+			return raiseError("Cannot assign to constant: %s", name.Value)
+		}
+
+		current, _ := evaluator.symbolTable.Get(name.Value)
+		var value symbol.Symbol
+		switch current.(type) {
+		case nil, *symbol.Integer:
+			value = evaluator.readInteger(evaluator.input)
+		case *symbol.Float:
+			value = readFloat(evaluator.input)
+		default:
+			return raiseError("Cannot read a %s value into %s", typeName(current), name.Value)
+		}
+
+		value = evaluator.convertNumber(value, current)
+		if isError(value) {
+			return value
+		}
+		if result := evaluator.symbolTable.Set(name.Value, value); isError(result) {
+			return result
+		}
+	}
+	return &symbol.Dummy{Value: ""}
+}
+
+// A false assertion stops the program with an error that shows the
+// condition and points at it
+func (evaluator *Evaluator) evaluateAssertStatement(assertStatement *ast.AssertStatement) symbol.Symbol {
+	if evaluator.DisableAssertions {
+		return &symbol.Dummy{Value: ""}
+	}
+	result, err := evaluator.evaluateCondition(assertStatement.Condition)
+	if err != nil {
+		return err
+	}
+	if result {
+		return &symbol.Dummy{Value: ""}
+	}
+
+	failed := raiseError("Assertion failed: %s", assertStatement.Source)
+	if assertStatement.Message != nil {
+		message := evaluator.Evaluate(assertStatement.Message)
+		if isError(message) {
+			return message
+		}
+		text, ok := message.(*symbol.String)
+		if !ok {
+			return raiseError("assert message must be a string, got %s", typeName(message))
+		}
+		failed = raiseError("Assertion failed: %s (%s)", text.Value, assertStatement.Source)
+	}
+	failed.Line = assertStatement.Start.Line
+	failed.Column = assertStatement.Start.Column
+	return failed
+}
+
+// Write output from the program, keeping to the output quota
+func (evaluator *Evaluator) write(output string) *symbol.Error {
+	if err := evaluator.countOutput(len(output)); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(evaluator.Output, output); err != nil {
+		return raiseError("Couldn't write output: %v", err)
+	}
+	return nil
+}
+
+// Count bytes that are about to be printed or written to a file
+// against the output limit
+func (evaluator *Evaluator) countOutput(size int) *symbol.Error {
+	if evaluator.MaxOutput > 0 && evaluator.outputBytes+size > evaluator.MaxOutput {
+		return limitExceeded("the program printed more than %d %s", evaluator.MaxOutput, plural(evaluator.MaxOutput, "byte"))
+	}
+	evaluator.outputBytes += size
+	return nil
+}
+
+// Call a builtin function with the values of its arguments
+func (evaluator *Evaluator) evaluateCall(call *ast.CallExpression) symbol.Symbol {
+	builtin, ok := builtins[call.Function.Value]
+	if !ok {
+		return raiseError("Unknown function: %s", call.Function.Value)
+	}
+
+	args := []symbol.Symbol{}
+	for _, argument := range call.Arguments {
+		value := evaluator.Evaluate(argument)
+		if isError(value) {
+			return value
+		}
+		args = append(args, copyValue(value))
+	}
+	return builtin(evaluator, args)
+}
+
+func (evaluator *Evaluator) evaluateInteger(integer *ast.Integer) symbol.Symbol {
+	if integer.Big != nil {
+		if !evaluator.BigIntegers {
+			return raiseError("Integer literal %s is too large for an int, use big integer mode", integer.Big)
+		}
+		return &symbol.Integer{Big: integer.Big}
+	}
+	if evaluator.BigIntegers {
+		return &symbol.Integer{Big: big.NewInt(integer.Value)}
+	}
+	return &symbol.Integer{Value: integer.Value}
+}
+
+func (evaluator *Evaluator) evaluatePrefix(operator string, right symbol.Symbol) symbol.Symbol {
+	if float, ok := right.(*symbol.Float); ok && operator == "-" {
+		return &symbol.Float{Value: -float.Value}
+	}
+	integer, ok := right.(*symbol.Integer)
+	if operator != "-" || !ok {
+		return raiseError("Unsupported operator for %s: %s", right.GetType(), operator)
+	}
+	// Negating a fixed-width value is subtracting it from zero
+	// once it has been promoted
+	if kind := promote(integer.Kind); kind != nil {
+		return evaluator.evaluateFixedInfix("-", kind, 0, evaluator.convertInteger(integer, kind).Value)
+	}
+	if integer.Big != nil || evaluator.BigIntegers {
+		return &symbol.Integer{Big: new(big.Int).Neg(integer.ToBig())}
+	}
+	// Negating INT64_MIN overflows
+	if evaluator.CheckedArithmetic && integer.Value == math.MinInt64 {
+		return raiseError("Integer overflow: -(%d)", integer.Value)
+	}
+	return &symbol.Integer{Value: -integer.Value}
+}
+
+func (evaluator *Evaluator) evaluateInfix(operator string, left, right symbol.Symbol) symbol.Symbol {
+	// Both operands must be of the same type, the operator is then applied
+	// by the helper function for that type
+	switch {
+	case left.GetType() == "INTEGER" && right.GetType() == "INTEGER":
+		leftInteger, rightInteger := left.(*symbol.Integer), right.(*symbol.Integer)
+		// A fixed-width operand decides the width of the operation even
+		// in big integer mode, where the other operand may be a big int
+		kind := commonKind(leftInteger.Kind, rightInteger.Kind)
+		if kind != nil {
+			leftInteger, rightInteger = evaluator.convertInteger(leftInteger, kind), evaluator.convertInteger(rightInteger, kind)
+			return evaluator.evaluateFixedInfix(operator, kind, leftInteger.Value, rightInteger.Value)
+		}
+		if leftInteger.Big != nil || rightInteger.Big != nil || evaluator.BigIntegers {
+			return evaluateBigInfix(operator, leftInteger.ToBig(), rightInteger.ToBig())
+		}
+		return evaluator.evaluateIntegerInfix(operator, leftInteger.Value, rightInteger.Value)
+	case isNumber(left) && isNumber(right):
+		// An int mixed with a double is promoted to a double
+		return evaluateFloatInfix(operator, toFloat(left), toFloat(right))
+	case left.GetType() == "STRING" && right.GetType() == "STRING":
+		return evaluateStringInfix(operator, left.(*symbol.String).Value, right.(*symbol.String).Value)
+	case left.GetType() == "BOOLEAN" && right.GetType() == "BOOLEAN":
+		return evaluateBooleanInfix(operator, left.(*symbol.Boolean).Value, right.(*symbol.Boolean).Value)
+	default:
+		return raiseError("Type mismatch: %s %s %s", left.GetType(), operator, right.GetType())
+	}
+}
+
+func (evaluator *Evaluator) evaluateIntegerInfix(operator string, leftValue, rightValue int64) symbol.Symbol {
+	switch operator {
+	case "+", "-", "*":
+		return evaluator.evaluateArithmetic(operator, leftValue, rightValue)
+	case "/", "%":
+		return evaluateDivision(operator, leftValue, rightValue)
+	case "<":
+		return toBoolean(leftValue < rightValue)
+	case ">":
+		return toBoolean(leftValue > rightValue)
+	case "<=":
+		return toBoolean(leftValue <= rightValue)
+	case ">=":
+		return toBoolean(leftValue >= rightValue)
+	case "==":
+		return toBoolean(leftValue == rightValue)
+	case "!=":
+		return toBoolean(leftValue != rightValue)
+	default:
+		return raiseError("Unsupported operator for integers: %s", operator)
+	}
+}
+
+// Addition, subtraction and multiplication wrap around on overflow like
+// the underlying int64 arithmetic does, unless checked arithmetic is on
+func (evaluator *Evaluator) evaluateArithmetic(operator string, leftValue, rightValue int64) symbol.Symbol {
+	var result int64
+	var overflow bool
 
 	switch operator {
 	case "+":
-		return &symbol.Integer{Value: leftValue + rightValue}
+		result = leftValue + rightValue
+		overflow = (rightValue > 0 && result < leftValue) || (rightValue < 0 && result > leftValue)
 	case "-":
-		return &symbol.Integer{Value: leftValue - rightValue}
+		result = leftValue - rightValue
+		overflow = (rightValue > 0 && result > leftValue) || (rightValue < 0 && result < leftValue)
 	case "*":
-		return &symbol.Integer{Value: leftValue * rightValue}
+		result = leftValue * rightValue
+		overflow = leftValue != 0 && (result/leftValue != rightValue || (leftValue == -1 && rightValue == math.MinInt64))
+	}
+
+	if evaluator.CheckedArithmetic && overflow {
+		return raiseError("Integer overflow: %d %s %d", leftValue, operator, rightValue)
+	}
+	return &symbol.Integer{Value: result}
+}
+
+// Division and modulo are checked before they are carried out - dividing by
+// zero would make Go panic, and INT64_MIN / -1 overflows, which is undefined
+// behaviour in C++. Both are reported as runtime errors instead
+func evaluateDivision(operator string, leftValue, rightValue int64) symbol.Symbol {
+	if rightValue == 0 {
+		return divisionByZero(operator)
+	}
+	if leftValue == math.MinInt64 && rightValue == -1 {
+		return raiseError("Integer overflow: %d %s -1", leftValue, operator)
+	}
+	if operator == "%" {
+		return &symbol.Integer{Value: leftValue % rightValue}
+	}
+	return &symbol.Integer{Value: leftValue / rightValue}
+}
+
+// Big integers can't overflow, but use the same truncating division and
+// remainder as int64 so that results match the default mode
+func evaluateBigInfix(operator string, leftValue, rightValue *big.Int) symbol.Symbol {
+	result := new(big.Int)
+	switch operator {
+	case "+":
+		return &symbol.Integer{Big: result.Add(leftValue, rightValue)}
+	case "-":
+		return &symbol.Integer{Big: result.Sub(leftValue, rightValue)}
+	case "*":
+		return &symbol.Integer{Big: result.Mul(leftValue, rightValue)}
 	case "/":
-		return &symbol.Integer{Value: leftValue / rightValue}
+		if rightValue.Sign() == 0 {
+			return divisionByZero(operator)
+		}
+		return &symbol.Integer{Big: result.Quo(leftValue, rightValue)}
 	case "%":
-		return &symbol.Integer{Value: leftValue % rightValue}
+		if rightValue.Sign() == 0 {
+			return divisionByZero(operator)
+		}
+		return &symbol.Integer{Big: result.Rem(leftValue, rightValue)}
+	case "<":
+		return toBoolean(leftValue.Cmp(rightValue) < 0)
+	case ">":
+		return toBoolean(leftValue.Cmp(rightValue) > 0)
+	case "<=":
+		return toBoolean(leftValue.Cmp(rightValue) <= 0)
+	case ">=":
+		return toBoolean(leftValue.Cmp(rightValue) >= 0)
+	case "==":
+		return toBoolean(leftValue.Cmp(rightValue) == 0)
+	case "!=":
+		return toBoolean(leftValue.Cmp(rightValue) != 0)
+	default:
+		return raiseError("Unsupported operator for integers: %s", operator)
+	}
+}
+
+func divisionByZero(operator string) *symbol.Error {
+	if operator == "%" {
+		return raiseError("Modulo by zero")
+	}
+	return raiseError("Division by zero")
+}
+
+/*
+==========================================
+Evaluating fixed-width integer expressions
+==========================================
+*/
+
+// int32_t is the type C++ promotes the narrower types to before doing
+// arithmetic with them
+var promotedKind = symbol.IntegerKinds["int32_t"]
+
+// Mask for the low 64 bits of a big integer
+var uint64Mask = new(big.Int).SetUint64(math.MaxUint64)
+
+// Integer promotion - types narrower than 32 bits become int32_t.
+// Plain ints have a nil kind and are left alone
+func promote(kind *symbol.IntegerKind) *symbol.IntegerKind {
+	if kind != nil && kind.Bits < 32 {
+		return promotedKind
+	}
+	return kind
+}
+
+func kindName(kind *symbol.IntegerKind) string {
+	if kind == nil {
+		return "int"
+	}
+	return kind.Name
+}
+
+// Get the width and signedness of a kind, plain ints are signed 64-bit
+func kindInfo(kind *symbol.IntegerKind) (uint, bool) {
+	if kind == nil {
+		return 64, false
+	}
+	return kind.Bits, kind.Unsigned
+}
+
+// Work out the type both operands of an arithmetic operator are converted
+// to, following the usual arithmetic conversions of C++. A nil kind means
+// the operation is done on plain ints
+func commonKind(left, right *symbol.IntegerKind) *symbol.IntegerKind {
+	left, right = promote(left), promote(right)
+	leftBits, leftUnsigned := kindInfo(left)
+	rightBits, rightUnsigned := kindInfo(right)
+
+	switch {
+	case leftUnsigned == rightUnsigned && leftBits >= rightBits:
+		return left
+	case leftUnsigned == rightUnsigned:
+		return right
+	// When the signs differ the unsigned type wins unless the signed
+	// type is wider and can hold all of its values
+	case leftUnsigned && leftBits >= rightBits, rightUnsigned && rightBits < leftBits:
+		return left
+	default:
+		return right
+	}
+}
+
+// Convert an integer to the given kind, truncating it if the kind is
+// narrower than the value. Converting to a nil kind gives a plain int
+func (evaluator *Evaluator) convertInteger(integer *symbol.Integer, kind *symbol.IntegerKind) *symbol.Integer {
+	if integer.Kind == kind {
+		return integer
+	}
+	value := integer.Value
+	if integer.Big != nil {
+		value = int64(new(big.Int).And(integer.Big, uint64Mask).Uint64())
+	}
+	if kind == nil {
+		if evaluator.BigIntegers {
+			return &symbol.Integer{Big: integer.ToBig()}
+		}
+		return &symbol.Integer{Value: value}
+	}
+	return &symbol.Integer{Value: kind.Wrap(value), Kind: kind}
+}
+
+// Apply an operator to two values that have already been converted to a
+// fixed-width kind. Signed kinds are narrower than int64 so the exact result
+// is worked out first, then wrapped to the width of the kind
+func (evaluator *Evaluator) evaluateFixedInfix(operator string, kind *symbol.IntegerKind, leftValue, rightValue int64) symbol.Symbol {
+	if kind.Unsigned {
+		return evaluateUnsignedInfix(operator, kind, uint64(leftValue), uint64(rightValue))
+	}
+
+	var result int64
+	switch operator {
+	case "+":
+		result = leftValue + rightValue
+	case "-":
+		result = leftValue - rightValue
+	case "*":
+		result = leftValue * rightValue
+	case "/", "%":
+		quotient := evaluateDivision(operator, leftValue, rightValue)
+		integer, ok := quotient.(*symbol.Integer)
+		if !ok {
+			return quotient
+		}
+		// INT32_MIN / -1 doesn't fit, which is undefined behaviour
+		if kind.Wrap(integer.Value) != integer.Value {
+			return raiseError("Integer overflow: %d %s %d", leftValue, operator, rightValue)
+		}
+		return &symbol.Integer{Value: integer.Value, Kind: kind}
+	default:
+		return evaluator.evaluateIntegerInfix(operator, leftValue, rightValue)
+	}
+
+	if evaluator.CheckedArithmetic && kind.Wrap(result) != result {
+		return raiseError("Integer overflow: %d %s %d", leftValue, operator, rightValue)
+	}
+	return &symbol.Integer{Value: kind.Wrap(result), Kind: kind}
+}
+
+// Unsigned arithmetic always wraps around, it never overflows in C++
+func evaluateUnsignedInfix(operator string, kind *symbol.IntegerKind, leftValue, rightValue uint64) symbol.Symbol {
+	var result uint64
+	switch operator {
+	case "+":
+		result = leftValue + rightValue
+	case "-":
+		result = leftValue - rightValue
+	case "*":
+		result = leftValue * rightValue
+	case "/", "%":
+		if rightValue == 0 {
+			return divisionByZero(operator)
+		}
+		if operator == "%" {
+			result = leftValue % rightValue
+		} else {
+			result = leftValue / rightValue
+		}
+	case "<":
+		return toBoolean(leftValue < rightValue)
+	case ">":
+		return toBoolean(leftValue > rightValue)
+	case "<=":
+		return toBoolean(leftValue <= rightValue)
+	case ">=":
+		return toBoolean(leftValue >= rightValue)
+	case "==":
+		return toBoolean(leftValue == rightValue)
+	case "!=":
+		return toBoolean(leftValue != rightValue)
+	default:
+		return raiseError("Unsupported operator for integers: %s", operator)
+	}
+	return &symbol.Integer{Value: kind.Wrap(int64(result)), Kind: kind}
+}
+
+/*
+=============================
+Evaluating double expressions
+=============================
+*/
+
+func isNumber(value symbol.Symbol) bool {
+	return value.GetType() == "INTEGER" || value.GetType() == "FLOAT"
+}
+
+// Get the value of a number as a double
+func toFloat(value symbol.Symbol) float64 {
+	switch number := value.(type) {
+	case *symbol.Float:
+		return number.Value
+	case *symbol.Integer:
+		if number.Big != nil {
+			float, _ := new(big.Float).SetInt(number.Big).Float64()
+			return float
+		}
+		if number.Kind != nil && number.Kind.Unsigned {
+			return float64(uint64(number.Value))
+		}
+		return float64(number.Value)
+	default:
+		return 0
+	}
+}
+
+// Doubles follow IEEE 754 like they do in C++, so dividing by
+// zero gives inf or nan rather than an error
+func evaluateFloatInfix(operator string, leftValue, rightValue float64) symbol.Symbol {
+	switch operator {
+	case "+":
+		return &symbol.Float{Value: leftValue + rightValue}
+	case "-":
+		return &symbol.Float{Value: leftValue - rightValue}
+	case "*":
+		return &symbol.Float{Value: leftValue * rightValue}
+	case "/":
+		return &symbol.Float{Value: leftValue / rightValue}
+	case "<":
+		return toBoolean(leftValue < rightValue)
+	case ">":
+		return toBoolean(leftValue > rightValue)
+	case "<=":
+		return toBoolean(leftValue <= rightValue)
+	case ">=":
+		return toBoolean(leftValue >= rightValue)
+	case "==":
+		return toBoolean(leftValue == rightValue)
+	case "!=":
+		return toBoolean(leftValue != rightValue)
+	default:
+		return raiseError("Unsupported operator for doubles: %s", operator)
+	}
+}
+
+// Convert a double to an integer of the given kind by truncating it towards
+// zero. Values the integer can't hold are undefined behaviour in C++, so
+// they are reported as runtime errors
+func (evaluator *Evaluator) floatToInteger(value float64, kind *symbol.IntegerKind) symbol.Symbol {
+	truncated := math.Trunc(value)
+	if math.IsNaN(truncated) || math.IsInf(truncated, 0) {
+		return raiseError("Cannot convert %s to %s", (&symbol.Float{Value: value}).GetValue(), kindName(kind))
+	}
+	if kind == nil && evaluator.BigIntegers {
+		integer, _ := big.NewFloat(truncated).Int(nil)
+		return &symbol.Integer{Big: integer}
+	}
+
+	bits, unsigned := kindInfo(kind)
+	limit := math.Ldexp(1, int(bits))
+	lowest := 0.0
+	if !unsigned {
+		limit /= 2
+		lowest = -limit
+	}
+	if truncated < lowest || truncated >= limit {
+		return raiseError("%s is out of range for %s", (&symbol.Float{Value: value}).GetValue(), kindName(kind))
+	}
+	if unsigned {
+		return &symbol.Integer{Value: int64(uint64(truncated)), Kind: kind}
+	}
+	return &symbol.Integer{Value: int64(truncated), Kind: kind}
+}
+
+// Convert a number to the type of the value it is replacing - ints and
+// doubles are converted to each other and integers are converted to the
+// kind of the target. Values of other types are returned unchanged
+func (evaluator *Evaluator) convertNumber(value, target symbol.Symbol) symbol.Symbol {
+	switch target := target.(type) {
+	case *symbol.Integer:
+		switch number := value.(type) {
+		case *symbol.Float:
+			return evaluator.floatToInteger(number.Value, target.Kind)
+		case *symbol.Integer:
+			return evaluator.convertInteger(number, target.Kind)
+		}
+	case *symbol.Float:
+		if _, ok := value.(*symbol.Integer); ok {
+			return &symbol.Float{Value: toFloat(value)}
+		}
+	}
+	return value
+}
+
+// Get the value a variable statement stores into - a zero value of the
+// declared type, or the current value of the variable for an assignment
+func (evaluator *Evaluator) variableTarget(varStatement *ast.VariableStatement) symbol.Symbol {
+	if varStatement.Type != "" {
+		return zeroValue(varStatement.Type, varStatement.Struct)
+	}
+	current, _ := evaluator.symbolTable.Get(varStatement.Name.Value)
+	return current
+}
+
+func evaluateStringInfix(operator string, leftValue, rightValue string) symbol.Symbol {
+	// Strings can be concatenated and compared lexicographically like std::string
+	switch operator {
+	case "+":
+		return &symbol.String{Value: leftValue + rightValue}
 	case "<":
-		result := evaluateToBooleanInteger(leftValue < rightValue)
-		return &symbol.Integer{Value: result}
+		return toBoolean(leftValue < rightValue)
 	case ">":
-		result := evaluateToBooleanInteger(leftValue > rightValue)
-		return &symbol.Integer{Value: result}
+		return toBoolean(leftValue > rightValue)
 	case "<=":
-		result := evaluateToBooleanInteger(leftValue <= rightValue)
-		return &symbol.Integer{Value: result}
+		return toBoolean(leftValue <= rightValue)
 	case ">=":
-		result := evaluateToBooleanInteger(leftValue >= rightValue)
-		return &symbol.Integer{Value: result}
+		return toBoolean(leftValue >= rightValue)
 	case "==":
-		result := evaluateToBooleanInteger(leftValue == rightValue)
-		return &symbol.Integer{Value: result}
+		return toBoolean(leftValue == rightValue)
 	case "!=":
-		result := evaluateToBooleanInteger(leftValue != rightValue)
-		return &symbol.Integer{Value: result}
-	case "&&":
-		return evaluateBooleanInfix(operator, leftValue, rightValue)
-	case "||":
-		return evaluateBooleanInfix(operator, leftValue, rightValue)
+		return toBoolean(leftValue != rightValue)
+	default:
+		return raiseError("Unsupported operator for strings: %s", operator)
+	}
+}
+
+/*
+=======================
+Struct and type helpers
+=======================
+*/
+
+// Create the value a variable of the given type holds when it is declared
+// without an initialiser - fields of structs are set to their zero values
+func zeroValue(typeName string, structDef *ast.StructStatement) symbol.Symbol {
+	if structDef != nil {
+		structure := &symbol.Struct{TypeName: structDef.Name.Value, Fields: make(map[string]symbol.Symbol)}
+		for _, field := range structDef.Fields {
+			structure.FieldNames = append(structure.FieldNames, field.Name.Value)
+			structure.Fields[field.Name.Value] = zeroValue(field.Type, field.Struct)
+		}
+		return structure
+	}
+	switch typeName {
+	case "bool":
+		return toBoolean(false)
+	case "double":
+		return &symbol.Float{Value: 0}
+	default:
+		return &symbol.Integer{Kind: symbol.IntegerKinds[typeName]}
+	}
+}
+
+// Structs are copied whenever they are stored so that no two
+// variables ever share the same struct
+func copyValue(value symbol.Symbol) symbol.Symbol {
+	if structure, ok := value.(*symbol.Struct); ok {
+		return structure.Copy()
+	}
+	return value
+}
+
+// Get the name of the type of a value - for structs this
+// is the name of the struct rather than STRUCT
+func typeName(value symbol.Symbol) string {
+	if structure, ok := value.(*symbol.Struct); ok {
+		return structure.TypeName
+	}
+	return value.GetType()
+}
+
+func sameType(left, right symbol.Symbol) bool {
+	return typeName(left) == typeName(right)
+}
+
+// Find the variable at the start of a chain of field accesses, e.g. p in p.a.b
+func rootIdentifier(expression ast.Expression) *ast.Identifier {
+	switch node := expression.(type) {
+	case *ast.Identifier:
+		return node
+	case *ast.MemberExpression:
+		return rootIdentifier(node.Object)
 	default:
 		return nil
 	}
@@ -159,42 +1120,58 @@ Evaluating boolean values
 =========================
 */
 
-func evaluateBooleanInfix(operator string, left, right int64) symbol.Symbol {
+// && and || short-circuit like they do in C++ - the right operand is
+// only evaluated when the left one doesn't decide the result, so guards
+// such as x != 0 && 10 / x > 1 are safe
+func (evaluator *Evaluator) evaluateLogical(infix *ast.InfixExpression) symbol.Symbol {
+	left, err := evaluator.evaluateCondition(infix.Left)
+	if err != nil {
+		return err
+	}
+	if left == (infix.Operator == "||") {
+		return toBoolean(left)
+	}
 
-	// Set the left and right values to actual boolean values first
-	// This avoids the error that arises from trying to compare int64
-	// using the && and || operators
-	leftBoolValue := setBooleanValue(left)
-	rightBoolValue := setBooleanValue(right)
+	right, err := evaluator.evaluateCondition(infix.Right)
+	if err != nil {
+		return err
+	}
+	return toBoolean(right)
+}
 
-	switch operator {
-	case "&&":
-		result := leftBoolValue && rightBoolValue
-		if result {
-			return &symbol.Integer{Value: 1}
-		}
-		return &symbol.Integer{Value: 0}
-	case "||":
-		result := leftBoolValue || rightBoolValue
-		if result {
-			return &symbol.Integer{Value: 1}
-		}
-		return &symbol.Integer{Value: 0}
+// Evaluate an expression that is being tested as a condition. As in C++
+// a bool is used as it is and any number that isn't zero is true
+func (evaluator *Evaluator) evaluateCondition(expression ast.Expression) (bool, *symbol.Error) {
+	value := evaluator.Evaluate(expression)
+	switch condition := value.(type) {
+	case *symbol.Error:
+		return false, condition
+	case *symbol.Boolean:
+		return condition.Value, nil
+	case *symbol.Integer:
+		if condition.Big != nil {
+			return condition.Big.Sign() != 0, nil
+		}
+		return condition.Value != 0, nil
+	case *symbol.Float:
+		return condition.Value != 0, nil
 	default:
-		return nil
+		return false, raiseError("Cannot use a %s value as a condition", typeName(value))
 	}
 }
 
-func evaluateToBooleanInteger(expression bool) int64 {
-	if expression {
-		return 1
+func evaluateBooleanInfix(operator string, left, right bool) symbol.Symbol {
+	switch operator {
+	case "==":
+		return toBoolean(left == right)
+	case "!=":
+		return toBoolean(left != right)
+	default:
+		return raiseError("Unsupported operator for bools: %s", operator)
 	}
-	return 0
 }
 
-func setBooleanValue(val int64) bool {
-	if val == 1 {
-		return true
-	}
-	return false
+// Convert a Go bool into a C-- Boolean symbol
+func toBoolean(value bool) *symbol.Boolean {
+	return &symbol.Boolean{Value: value}
 }
@@ -0,0 +1,231 @@
+package evaluator
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/sedexdev/go-interpreter/internal/lexer"
+	"github.com/sedexdev/go-interpreter/internal/parser"
+	"github.com/sedexdev/go-interpreter/internal/symbol"
+)
+
+// Parse a program and run it with a new evaluator, returning what it
+// printed and the value it finished with
+func runProgram(t *testing.T, code string, configure func(*Evaluator)) (string, symbol.Symbol) {
+	t.Helper()
+	programParser := parser.CreateParser(lexer.CreateLexer(code))
+	program := programParser.ParseProgram()
+	if errors := programParser.GetErrors(); len(errors) > 0 {
+		t.Fatalf("program has syntax errors: %v", errors)
+	}
+
+	var output bytes.Buffer
+	programEvaluator := CreateEvaluator(symbol.CreateSymbolTable())
+	programEvaluator.Output = &output
+	if configure != nil {
+		configure(programEvaluator)
+	}
+	result := programEvaluator.Run(context.Background(), program)
+	return output.String(), result
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"guarded division", "int x = 0; print x != 0 && 10 / x > 1;", "0\n"},
+		{"guard passes", "int x = 2; print x != 0 && 10 / x > 1;", "1\n"},
+		{"or skips undefined variable", "bool y = true; print y || undefinedVar;", "1\n"},
+		{"and binds tighter than or", "print true || false && false;", "1\n"},
+		{"and binds tighter than or on the right", "print false || true && false;", "0\n"},
+		{"parentheses group or first", "print (true || false) && false;", "0\n"},
+		{"nested parentheses", "print ((1 < 2) && ((2 < 3) || (1 / 0 > 0)));", "1\n"},
+		{"mixed chain", "int a = 1; int b = 0; print a && b || a > b && b == 0;", "1\n"},
+		{"numbers as conditions", "print 2 && 0.5, 0 || 0.0;", "1 0\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, result := runProgram(t, test.code, nil)
+			if err, ok := result.(*symbol.Error); ok {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output != test.want {
+				t.Errorf("printed %q, want %q", output, test.want)
+			}
+		})
+	}
+}
+
+func TestLogicalOperatorsSkipRightOperand(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"and skips division by zero", "print false && 1 / 0 > 0;"},
+		{"or skips division by zero", "print true || 1 / 0 > 0;"},
+		{"and skips exit", "print 0 && exit(3) == 0;"},
+		{"or skips exit", "print 1 || exit(3) == 0;"},
+		{"nested chain skips exit", "print (false && exit(3) == 0) || (true || exit(4) == 0);"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, result := runProgram(t, test.code, nil)
+			if err, ok := result.(*symbol.Error); ok {
+				t.Fatalf("right operand was evaluated: %v", err)
+			}
+		})
+	}
+}
+
+func TestLogicalOperatorsEvaluateRightOperand(t *testing.T) {
+	_, result := runProgram(t, "print true && exit(3) == 0;", nil)
+	err, ok := result.(*symbol.Error)
+	if !ok || !err.Exit || err.ExitCode != 3 {
+		t.Fatalf("got %v, want exit(3) to be called", result)
+	}
+
+	_, result = runProgram(t, "print false || 1 / 0 > 0;", nil)
+	if err, ok := result.(*symbol.Error); !ok || err.Message != "Division by zero" {
+		t.Fatalf("got %v, want a division by zero error", result)
+	}
+}
+
+func TestConstantDeclarationInLoop(t *testing.T) {
+	code := "int i = 0; while (i < 3) { const int k = i * 2; print k; i = i + 1; }"
+	output, result := runProgram(t, code, nil)
+	if err, ok := result.(*symbol.Error); ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "0\n2\n4\n"; output != want {
+		t.Errorf("printed %q, want %q", output, want)
+	}
+}
+
+func TestAssignToConstant(t *testing.T) {
+	programEvaluator := CreateEvaluator(symbol.CreateSymbolTable())
+	programEvaluator.symbolTable.SetConstant("k", &symbol.Integer{Value: 1})
+	program := parser.CreateParser(lexer.CreateLexer("k = 2;")).ParseProgram()
+	result := programEvaluator.Run(context.Background(), program)
+	if err, ok := result.(*symbol.Error); !ok || err.Message != "Cannot assign to constant: k" {
+		t.Fatalf("got %v, want an error assigning to the constant", result)
+	}
+}
+
+// Integer modes that fixed-width types must behave the same in
+var integerModes = map[string]func(*Evaluator){
+	"default": nil,
+	"big":     func(evaluator *Evaluator) { evaluator.BigIntegers = true },
+}
+
+func TestFixedWidthIntegers(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		// Values are truncated when they are stored
+		{"int8_t truncated on store", "int8_t a = 300; print a;", "44\n"},
+		{"int16_t truncated on store", "int16_t a = 40000; print a;", "-25536\n"},
+		{"uint8_t wraps negative values", "uint8_t a = -1; print a;", "255\n"},
+		{"assignment truncates", "int8_t a = 0; a = 200; print a;", "-56\n"},
+
+		// Narrow types are promoted to int32_t in arithmetic
+		{"int8_t sum is promoted", "int8_t a = 100; int8_t b = 100; print a + b;", "200\n"},
+		{"uint8_t sum is promoted", "uint8_t a = 200; uint8_t b = 100; print a + b;", "300\n"},
+		{"int16_t product is promoted", "int16_t a = 32767; print a * a;", "1073676289\n"},
+		{"negating uint8_t is promoted", "uint8_t a = 1; print -a;", "-1\n"},
+		{"int32_t wraps", "int32_t a = 2147483647; int32_t b = 1; print a + b;", "-2147483648\n"},
+
+		// Mixing signed and unsigned follows the usual arithmetic conversions
+		{"int32_t compared with uint32_t", "int32_t s = -1; uint32_t u = 1; print s < u;", "0\n"},
+		{"int compared with uint32_t", "int s = -1; uint32_t u = 1; print s < u;", "1\n"},
+		{"int compared with uint64_t", "uint64_t u = 1; print -1 < u;", "0\n"},
+		{"uint32_t wraps", "uint32_t a = 4294967295; uint32_t b = 1; print a + b;", "0\n"},
+		{"uint32_t plus int is an int", "uint32_t a = 4294967295; print a + 1;", "4294967296\n"},
+		{"uint64_t wraps below zero", "uint64_t u = 0; print u - 1;", "18446744073709551615\n"},
+		{"uint64_t division", "uint64_t u = -1; print u / 2;", "9223372036854775807\n"},
+
+		// Unsigned values are printed as unsigned
+		{"uint64_t printed unsigned", "uint64_t a = -1; print a;", "18446744073709551615\n"},
+		{"uint32_t printed unsigned", "uint32_t a = -1; print a;", "4294967295\n"},
+
+		// Doubles are truncated towards zero
+		{"double truncated to int8_t", "int8_t a = 127.9; int8_t b = -3.9; print a, b;", "127 -3\n"},
+		{"double truncated to uint16_t", "uint16_t a = 65535.5; print a;", "65535\n"},
+
+		// Math builtins keep the kind of their arguments
+		{"abs of wrapped uint64_t", "uint64_t z = 0; print abs(z - 1);", "18446744073709551615\n"},
+		{"isqrt of uint64_t", "uint64_t u = -1; print isqrt(u);", "4294967295\n"},
+		{"gcd of uint32_t", "uint32_t a = 4294967295; print gcd(a, 6);", "3\n"},
+		{"pow of uint8_t is promoted", "uint8_t a = 255; print pow(a, 2);", "65025\n"},
+	}
+	for mode, configure := range integerModes {
+		for _, test := range tests {
+			t.Run(mode+"/"+test.name, func(t *testing.T) {
+				output, result := runProgram(t, test.code, configure)
+				if err, ok := result.(*symbol.Error); ok {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if output != test.want {
+					t.Errorf("printed %q, want %q", output, test.want)
+				}
+			})
+		}
+	}
+}
+
+func TestFixedWidthRangeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"double above int8_t", "int8_t a = 300.5;", "300.5 is out of range for int8_t"},
+		{"negative double in uint8_t", "uint8_t a = -1.5;", "-1.5 is out of range for uint8_t"},
+		{"double above uint64_t", "uint64_t a = 1e20;", "1e+20 is out of range for uint64_t"},
+		{"nan in int16_t", "double d = 0.0 / 0.0; int16_t a = d;", "Cannot convert nan to int16_t"},
+	}
+	for mode, configure := range integerModes {
+		for _, test := range tests {
+			t.Run(mode+"/"+test.name, func(t *testing.T) {
+				_, result := runProgram(t, test.code, configure)
+				if err, ok := result.(*symbol.Error); !ok || err.Message != test.want {
+					t.Fatalf("got %v, want error %q", result, test.want)
+				}
+			})
+		}
+	}
+}
+
+func TestLimitMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		configure func(*Evaluator)
+		want      string
+	}{
+		{"variables", "a = 1; b = 2;", func(evaluator *Evaluator) {
+			evaluator.symbolTable.MaxVariables = 1
+		}, "the variable limit of 1 was reached"},
+		{"one step", "a = 1; b = 2;", func(evaluator *Evaluator) {
+			evaluator.MaxSteps = 1
+		}, "the program took more than 1 step"},
+		{"one byte", "print 12;", func(evaluator *Evaluator) {
+			evaluator.MaxOutput = 1
+		}, "the program printed more than 1 byte"},
+		{"bytes", "print 123;", func(evaluator *Evaluator) {
+			evaluator.MaxOutput = 2
+		}, "the program printed more than 2 bytes"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, result := runProgram(t, test.code, test.configure)
+			if err, ok := result.(*symbol.Error); !ok || !err.LimitExceeded || err.Message != test.want {
+				t.Fatalf("got %v, want the limit error %q", result, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package evaluator
+
+import (
+	"bufio"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/sedexdev/go-interpreter/internal/symbol"
+)
+
+/*
+=============
+File builtins
+=============
+*/
+
+// The most files a program can have open at once
+const maxOpenFiles = 16
+
+// openFile is a file opened by fopen(). Files are opened either for
+// reading or for writing, so only one of reader and writer is set
+type openFile struct {
+	name   string
+	file   *os.File
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// Flags passed to os.OpenFile for each mode fopen() accepts
+var fileModes = map[string]int{
+	"r": os.O_RDONLY,
+	"w": os.O_WRONLY | os.O_CREATE | os.O_TRUNC,
+	"a": os.O_WRONLY | os.O_CREATE | os.O_APPEND,
+}
+
+// fopen(path, mode) opens a file in the file root and gives a handle for
+// it, e.g. int data = fopen("data.txt", "r"); The mode is "r" to read,
+// "w" to write over the file or "a" to add to the end of it
+func builtinFopen(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if len(args) != 2 {
+		return raiseError("fopen takes 2 arguments, got %d", len(args))
+	}
+	name, nameOk := args[0].(*symbol.String)
+	mode, modeOk := args[1].(*symbol.String)
+	if !nameOk || !modeOk {
+		return raiseError("fopen needs a path and a mode, got %s and %s", typeName(args[0]), typeName(args[1]))
+	}
+	flags, ok := fileModes[mode.Value]
+	if !ok {
+		return raiseError("fopen mode must be \"r\", \"w\" or \"a\", got %q", mode.Value)
+	}
+	if len(evaluator.files) >= maxOpenFiles {
+		return limitExceeded("the program has more than %d files open", maxOpenFiles)
+	}
+
+	file, err := evaluator.openInRoot(name.Value, flags)
+	if err != nil {
+		return err
+	}
+	if info, statErr := file.Stat(); statErr == nil && info.IsDir() {
+		file.Close()
+		return raiseError("Couldn't open %q: it is a directory", name.Value)
+	}
+
+	opened := &openFile{name: name.Value, file: file}
+	if mode.Value == "r" {
+		opened.reader = bufio.NewReader(file)
+	} else {
+		opened.writer = bufio.NewWriter(file)
+	}
+	if evaluator.files == nil {
+		evaluator.files = make(map[int64]*openFile)
+	}
+	evaluator.nextHandle++
+	evaluator.files[evaluator.nextHandle] = opened
+	return evaluator.integerResult("fopen", big.NewInt(evaluator.nextHandle), nil)
+}
+
+// fread_int(handle) reads the next int from a file opened with "r".
+// Running out of data is an error, feof() can be checked first
+func builtinFreadInt(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	opened, err := evaluator.fileArg("fread_int", args, 1)
+	if err != nil {
+		return err
+	}
+	if opened.reader == nil {
+		return raiseError("%q isn't open for reading", opened.name)
+	}
+	return evaluator.readInteger(opened.reader)
+}
+
+// fwrite_int(handle, n) writes an int to a file opened with "w" or "a",
+// one per line so that fread_int() can read them back
+func builtinFwriteInt(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	opened, err := evaluator.fileArg("fwrite_int", args, 2)
+	if err != nil {
+		return err
+	}
+	if opened.writer == nil {
+		return raiseError("%q isn't open for writing", opened.name)
+	}
+	value, ok := args[1].(*symbol.Integer)
+	if !ok {
+		return raiseError("fwrite_int needs an int to write, got %s", typeName(args[1]))
+	}
+
+	line := value.GetValue() + "\n"
+	if err := evaluator.countOutput(len(line)); err != nil {
+		return err
+	}
+	if _, writeErr := opened.writer.WriteString(line); writeErr != nil {
+		return raiseError("Couldn't write to %q: %v", opened.name, unwrapPathError(writeErr))
+	}
+	return &symbol.Dummy{Value: ""}
+}
+
+// feof(handle) is true once nothing but whitespace is left in a file
+// opened for reading, like eof() is for input
+func builtinFeof(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	opened, err := evaluator.fileArg("feof", args, 1)
+	if err != nil {
+		return err
+	}
+	if opened.reader == nil {
+		return raiseError("%q isn't open for reading", opened.name)
+	}
+	readErr := skipInputSpace(opened.reader)
+	if readErr == io.EOF {
+		return toBoolean(true)
+	}
+	if readErr != nil {
+		return raiseError("Couldn't read %q: %v", opened.name, unwrapPathError(readErr))
+	}
+	return toBoolean(false)
+}
+
+// fclose(handle) closes a file, writing anything that is still buffered.
+// The handle can't be used again afterwards
+func builtinFclose(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	opened, err := evaluator.fileArg("fclose", args, 1)
+	if err != nil {
+		return err
+	}
+	delete(evaluator.files, args[0].(*symbol.Integer).ToBig().Int64())
+	return closeFile(opened)
+}
+
+/*
+=============================
+Helpers for the file builtins
+=============================
+*/
+
+// Find the open file for the handle passed as the first argument
+func (evaluator *Evaluator) fileArg(name string, args []symbol.Symbol, count int) (*openFile, *symbol.Error) {
+	if len(args) != count {
+		return nil, raiseError("%s takes %d %s, got %d", name, count, plural(count, "argument"), len(args))
+	}
+	handle, ok := args[0].(*symbol.Integer)
+	if !ok {
+		return nil, raiseError("%s needs a file handle, got %s", name, typeName(args[0]))
+	}
+	value := handle.ToBig()
+	opened, ok := evaluator.files[value.Int64()]
+	if !ok || !value.IsInt64() {
+		return nil, raiseError("%s isn't an open file handle", handle.GetValue())
+	}
+	return opened, nil
+}
+
+// Open a file given to fopen() inside the file root. The path must be
+// relative and can't leave the root. Files are opened through os.Root,
+// which also refuses symbolic links that lead out of the root, including
+// links to files that don't exist yet
+func (evaluator *Evaluator) openInRoot(name string, flags int) (*os.File, *symbol.Error) {
+	if evaluator.FileRoot == "" {
+		return nil, raiseError("File access is disabled")
+	}
+	if !filepath.IsLocal(name) {
+		return nil, raiseError("Path %q is outside the file root", name)
+	}
+
+	root, err := os.OpenRoot(evaluator.FileRoot)
+	if err != nil {
+		return nil, raiseError("Couldn't use the file root: %v", unwrapPathError(err))
+	}
+	defer root.Close()
+	file, err := root.OpenFile(name, flags, 0644)
+	if err != nil {
+		return nil, raiseError("Couldn't open %q: %v", name, unwrapPathError(err))
+	}
+	return file, nil
+}
+
+// Flush and close a file, reporting data that couldn't be written
+func closeFile(opened *openFile) symbol.Symbol {
+	var err error
+	if opened.writer != nil {
+		err = opened.writer.Flush()
+	}
+	if closeErr := opened.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return raiseError("Couldn't close %q: %v", opened.name, unwrapPathError(err))
+	}
+	return &symbol.Dummy{Value: ""}
+}
+
+// Close every file the program left open, returning the first error
+func (evaluator *Evaluator) closeFiles() *symbol.Error {
+	var firstErr *symbol.Error
+	for handle, opened := range evaluator.files {
+		if err, ok := closeFile(opened).(*symbol.Error); ok && firstErr == nil {
+			firstErr = err
+		}
+		delete(evaluator.files, handle)
+	}
+	return firstErr
+}
+
+// Errors from the os package include the full path on disk, which would
+// tell a program where the file root is. Only the reason is kept
+func unwrapPathError(err error) error {
+	if pathErr, ok := err.(*os.PathError); ok {
+		return pathErr.Err
+	}
+	return err
+}
@@ -0,0 +1,104 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sedexdev/go-interpreter/internal/symbol"
+)
+
+// Create a file root with a directory outside it, a link to that
+// directory and a link to a file in it that doesn't exist yet
+func createFileRoot(t *testing.T) (string, string) {
+	t.Helper()
+	base := t.TempDir()
+	root, outside := filepath.Join(base, "root"), filepath.Join(base, "outside")
+	for _, directory := range []string{root, outside} {
+		if err := os.Mkdir(directory, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("7\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("symbolic links aren't supported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "escaped.txt"), filepath.Join(root, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	return root, outside
+}
+
+func TestFileAccessIsDisabledByDefault(t *testing.T) {
+	_, result := runProgram(t, `int h = fopen("data.txt", "w");`, nil)
+	if err, ok := result.(*symbol.Error); !ok || err.Message != "File access is disabled" {
+		t.Fatalf("got %v, want file access to be disabled", result)
+	}
+}
+
+func TestFilesStayInsideRoot(t *testing.T) {
+	root, outside := createFileRoot(t)
+	tests := []struct {
+		name string
+		path string
+		mode string
+	}{
+		{"parent directory", "../outside/secret.txt", "r"},
+		{"parent directory after a subdirectory", "escape/../../outside/secret.txt", "r"},
+		{"absolute path", filepath.Join(outside, "secret.txt"), "r"},
+		{"directory link", "escape/secret.txt", "r"},
+		{"write through a directory link", "escape/new.txt", "w"},
+		{"dangling link", "dangling", "w"},
+		{"append to a dangling link", "dangling", "a"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code := `int h = fopen("` + filepath.ToSlash(test.path) + `", "` + test.mode + `"); fwrite_int(h, 42); fclose(h);`
+			_, result := runProgram(t, code, func(evaluator *Evaluator) {
+				evaluator.FileRoot = root
+			})
+			err, ok := result.(*symbol.Error)
+			if !ok || !strings.HasPrefix(err.Message, "Couldn't open") && !strings.HasPrefix(err.Message, "Path") {
+				t.Fatalf("got %v, want fopen to be refused", result)
+			}
+			if strings.Contains(err.Message, root) {
+				t.Errorf("error %q shows where the file root is", err.Message)
+			}
+			for _, name := range []string{"escaped.txt", "new.txt"} {
+				if _, statErr := os.Stat(filepath.Join(outside, name)); statErr == nil {
+					t.Errorf("%s was created outside the root", name)
+				}
+			}
+		})
+	}
+}
+
+func TestFilesReadBackWhatIsWritten(t *testing.T) {
+	root, _ := createFileRoot(t)
+	if err := os.Mkdir(filepath.Join(root, "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	code := `int out = fopen("data/numbers.txt", "w");
+fwrite_int(out, 3);
+fwrite_int(out, -4);
+fclose(out);
+int in = fopen("data/numbers.txt", "r");
+int sum = 0;
+while (feof(in) == false) {
+    sum = sum + fread_int(in);
+}
+fclose(in);
+print sum;`
+	output, result := runProgram(t, code, func(evaluator *Evaluator) {
+		evaluator.FileRoot = root
+	})
+	if err, ok := result.(*symbol.Error); ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "-1\n" {
+		t.Errorf("printed %q, want %q", output, "-1\n")
+	}
+}
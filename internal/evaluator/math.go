@@ -0,0 +1,305 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"math/rand/v2"
+
+	"github.com/sedexdev/go-interpreter/internal/symbol"
+)
+
+/*
+=============
+Math builtins
+=============
+*/
+
+// DEFAULTSEED is the seed rand() uses until srand() is called, so a
+// program that never calls srand() gives the same numbers every run
+const DEFAULTSEED = 1
+
+// The largest number of bits pow() will work out exactly, so that a
+// program can't use all the memory with something like pow(2, 1000000000)
+const maxPowBits = 1 << 20
+
+// Create the random number generator used by rand() and srand()
+func createRandom(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, 0))
+}
+
+// abs gives the magnitude of a number, e.g. abs(-3) is 3
+func builtinAbs(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if err := numberArgs("abs", args, 1); err != nil {
+		return err
+	}
+	if float, ok := args[0].(*symbol.Float); ok {
+		return &symbol.Float{Value: math.Abs(float.Value)}
+	}
+	if !evaluator.less(args[0], &symbol.Integer{Value: 0}) {
+		return args[0]
+	}
+	return evaluator.evaluatePrefix("-", args[0])
+}
+
+// min gives the smallest of two or more numbers, e.g. min(a, b, c)
+func builtinMin(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	return evaluator.pick("min", args, func(value, best symbol.Symbol) bool {
+		return evaluator.less(value, best)
+	})
+}
+
+// max gives the largest of two or more numbers, e.g. max(a, b, c)
+func builtinMax(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	return evaluator.pick("max", args, func(value, best symbol.Symbol) bool {
+		return evaluator.less(best, value)
+	})
+}
+
+// clamp(x, lo, hi) limits x to the range lo to hi
+func builtinClamp(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if err := numberArgs("clamp", args, 3); err != nil {
+		return err
+	}
+	value, low, high := args[0], args[1], args[2]
+	if evaluator.less(high, low) {
+		return raiseError("clamp range is empty, %s is greater than %s", low.GetValue(), high.GetValue())
+	}
+	if evaluator.less(value, low) {
+		value = low
+	} else if evaluator.less(high, value) {
+		value = high
+	}
+	return matchDoubles(value, args)
+}
+
+// sign gives -1, 0 or 1 depending on whether a number is
+// negative, zero or positive
+func builtinSign(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if err := numberArgs("sign", args, 1); err != nil {
+		return err
+	}
+	if float, ok := args[0].(*symbol.Float); ok && math.IsNaN(float.Value) {
+		return raiseError("sign of nan is undefined")
+	}
+	zero := &symbol.Integer{Value: 0}
+	switch {
+	case evaluator.less(args[0], zero):
+		return evaluator.integerResult("sign", big.NewInt(-1), nil)
+	case evaluator.less(zero, args[0]):
+		return evaluator.integerResult("sign", big.NewInt(1), nil)
+	default:
+		return evaluator.integerResult("sign", big.NewInt(0), nil)
+	}
+}
+
+// pow(base, exponent) raises a number to a power. Ints give an int that
+// overflows like * does, and doubles give a double like C++'s pow
+func builtinPow(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if err := numberArgs("pow", args, 2); err != nil {
+		return err
+	}
+	base, baseInteger := args[0].(*symbol.Integer)
+	exponent, exponentInteger := args[1].(*symbol.Integer)
+	if !baseInteger || !exponentInteger {
+		return &symbol.Float{Value: math.Pow(toFloat(args[0]), toFloat(args[1]))}
+	}
+
+	baseValue, exponentValue := base.ToBig(), exponent.ToBig()
+	if exponentValue.Sign() < 0 {
+		return raiseError("pow exponent must not be negative for ints, got %s, use a double instead", exponent.GetValue())
+	}
+	kind := promote(base.Kind)
+
+	// Only the low 64 bits are kept when the result wraps around,
+	// otherwise the result is worked out exactly
+	small := new(big.Int).Abs(baseValue).Cmp(big.NewInt(1)) <= 0
+	tooLarge := !small && (!exponentValue.IsInt64() || exponentValue.Int64() > maxPowBits ||
+		int64(baseValue.BitLen()-1)*exponentValue.Int64() > maxPowBits)
+	if !tooLarge {
+		return evaluator.integerResult("pow", new(big.Int).Exp(baseValue, exponentValue, nil), kind)
+	}
+	if kind == nil && evaluator.BigIntegers {
+		return raiseError("pow(%s, %s) is too large to work out", base.GetValue(), exponent.GetValue())
+	}
+	if evaluator.CheckedArithmetic && (kind == nil || !kind.Unsigned) {
+		return raiseError("Integer overflow: pow(%s, %s)", base.GetValue(), exponent.GetValue())
+	}
+	modulus := new(big.Int).Lsh(big.NewInt(1), 64)
+	result := new(big.Int).Exp(new(big.Int).Abs(baseValue), exponentValue, modulus)
+	if baseValue.Sign() < 0 && exponentValue.Bit(0) == 1 {
+		result.Neg(result)
+	}
+	return evaluator.integerResult("pow", result, kind)
+}
+
+// isqrt gives the square root of an int rounded down, e.g. isqrt(10) is 3
+func builtinIsqrt(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if err := integerArgs("isqrt", args, 1); err != nil {
+		return err
+	}
+	value := args[0].(*symbol.Integer)
+	if value.ToBig().Sign() < 0 {
+		return raiseError("isqrt of a negative number: %s", value.GetValue())
+	}
+	return evaluator.integerResult("isqrt", new(big.Int).Sqrt(value.ToBig()), resultKind(value))
+}
+
+// gcd gives the greatest common divisor of two ints, which is never
+// negative. gcd(0, 0) is 0
+func builtinGcd(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if err := integerArgs("gcd", args, 2); err != nil {
+		return err
+	}
+	left, right := args[0].(*symbol.Integer), args[1].(*symbol.Integer)
+	result := new(big.Int).GCD(nil, nil, left.ToBig(), right.ToBig())
+	return evaluator.integerResult("gcd", result, pairKind(left, right))
+}
+
+// lcm gives the least common multiple of two ints, which is never
+// negative. The lcm of 0 and anything is 0
+func builtinLcm(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if err := integerArgs("lcm", args, 2); err != nil {
+		return err
+	}
+	left, right := args[0].(*symbol.Integer), args[1].(*symbol.Integer)
+	result := new(big.Int)
+	if left.ToBig().Sign() != 0 && right.ToBig().Sign() != 0 {
+		gcd := new(big.Int).GCD(nil, nil, left.ToBig(), right.ToBig())
+		result.Mul(left.ToBig(), right.ToBig()).Abs(result).Quo(result, gcd)
+	}
+	return evaluator.integerResult("lcm", result, pairKind(left, right))
+}
+
+// rand(n) gives a random int from 0 up to but not including n. The
+// numbers are the same every run unless the program calls srand()
+func builtinRand(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if err := integerArgs("rand", args, 1); err != nil {
+		return err
+	}
+	bound := args[0].(*symbol.Integer)
+	limit := bound.ToBig()
+	if limit.Sign() <= 0 {
+		return raiseError("rand bound must be positive, got %s", bound.GetValue())
+	}
+	if !limit.IsUint64() {
+		return raiseError("rand bound is too large: %s", bound.GetValue())
+	}
+	result := new(big.Int).SetUint64(evaluator.random.Uint64N(limit.Uint64()))
+	return evaluator.integerResult("rand", result, resultKind(bound))
+}
+
+// srand(seed) restarts rand() from a seed, so the same
+// seed always gives the same numbers
+func builtinSrand(evaluator *Evaluator, args []symbol.Symbol) symbol.Symbol {
+	if err := integerArgs("srand", args, 1); err != nil {
+		return err
+	}
+	seed := new(big.Int).And(args[0].(*symbol.Integer).ToBig(), uint64Mask)
+	evaluator.random = createRandom(seed.Uint64())
+	return &symbol.Dummy{Value: ""}
+}
+
+/*
+=============================
+Helpers for the math builtins
+=============================
+*/
+
+// Check that a builtin was called with the right number of numbers
+func numberArgs(name string, args []symbol.Symbol, count int) *symbol.Error {
+	if len(args) != count {
+		return raiseError("%s takes %d %s, got %d", name, count, plural(count, "argument"), len(args))
+	}
+	for _, arg := range args {
+		if !isNumber(arg) {
+			return raiseError("%s needs numbers, got %s", name, typeName(arg))
+		}
+	}
+	return nil
+}
+
+// Check that a builtin was called with the right number of ints
+func integerArgs(name string, args []symbol.Symbol, count int) *symbol.Error {
+	if len(args) != count {
+		return raiseError("%s takes %d %s, got %d", name, count, plural(count, "argument"), len(args))
+	}
+	for _, arg := range args {
+		if arg.GetType() != "INTEGER" {
+			return raiseError("%s needs ints, got %s", name, typeName(arg))
+		}
+	}
+	return nil
+}
+
+func plural(count int, word string) string {
+	if count == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// Compare two numbers that are known to be numbers
+func (evaluator *Evaluator) less(left, right symbol.Symbol) bool {
+	result, ok := evaluator.evaluateInfix("<", left, right).(*symbol.Boolean)
+	return ok && result.Value
+}
+
+// Choose one of two or more numbers, replacing the best one so far
+// whenever better returns true
+func (evaluator *Evaluator) pick(name string, args []symbol.Symbol, better func(value, best symbol.Symbol) bool) symbol.Symbol {
+	if len(args) < 2 {
+		return raiseError("%s takes at least 2 arguments, got %d", name, len(args))
+	}
+	if err := numberArgs(name, args, len(args)); err != nil {
+		return err
+	}
+	best := args[0]
+	for _, value := range args[1:] {
+		if better(value, best) {
+			best = value
+		}
+	}
+	return matchDoubles(best, args)
+}
+
+// The result of a numeric builtin is a double when any of its
+// arguments is a double, the same as it is for arithmetic
+func matchDoubles(result symbol.Symbol, args []symbol.Symbol) symbol.Symbol {
+	for _, arg := range args {
+		if arg.GetType() == "FLOAT" {
+			return &symbol.Float{Value: toFloat(result)}
+		}
+	}
+	return result
+}
+
+// The kind an int result takes when it comes from one argument
+func resultKind(value *symbol.Integer) *symbol.IntegerKind {
+	return promote(value.Kind)
+}
+
+// The kind an int result takes when it comes from two arguments
+func pairKind(left, right *symbol.Integer) *symbol.IntegerKind {
+	return commonKind(left.Kind, right.Kind)
+}
+
+// Store the exact result of a builtin in an int of the given kind. The
+// result wraps around if it doesn't fit, unless checked arithmetic is on
+// and the kind is signed, in which case it is an overflow error
+func (evaluator *Evaluator) integerResult(name string, value *big.Int, kind *symbol.IntegerKind) symbol.Symbol {
+	if kind == nil && evaluator.BigIntegers {
+		return &symbol.Integer{Big: value}
+	}
+	wrapped := int64(new(big.Int).And(value, uint64Mask).Uint64())
+	if kind == nil {
+		if evaluator.CheckedArithmetic && !value.IsInt64() {
+			return raiseError("Integer overflow: %s result %s is too large for an int", name, value)
+		}
+		return &symbol.Integer{Value: wrapped}
+	}
+	fits := value.IsInt64() && kind.Wrap(value.Int64()) == value.Int64()
+	if evaluator.CheckedArithmetic && !kind.Unsigned && !fits {
+		return raiseError("Integer overflow: %s result %s is too large for %s", name, value, kind.Name)
+	}
+	return &symbol.Integer{Value: kind.Wrap(wrapped), Kind: kind}
+}
@@ -0,0 +1,152 @@
+package interpreter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sedexdev/go-interpreter/internal/checker"
+	"github.com/sedexdev/go-interpreter/internal/evaluator"
+	"github.com/sedexdev/go-interpreter/internal/lexer"
+	"github.com/sedexdev/go-interpreter/internal/parser"
+	"github.com/sedexdev/go-interpreter/internal/symbol"
+)
+
+/*
+===========================
+Define the Interpreter type
+===========================
+*/
+
+// Interpreter takes C-- programs from source code through to their output.
+// Each interpreter owns its symbol table and the writers it outputs to, so
+// several can run in one process without sharing state or interleaving
+type Interpreter struct {
+	// Stdout receives the output of print statements and Stderr receives
+	// errors and warnings about the program
+	Stdout io.Writer
+	Stderr io.Writer
+	// Options passed on to the parser and evaluator, see their
+	// documentation for details
+	LegacyMode        bool
+	CheckedArithmetic bool
+	BigIntegers       bool
+	DisableAssertions bool
+	MaxSteps          int
+	Timeout           time.Duration
+	MaxVariables      int
+	MaxOutput         int
+	MaxDepth          int
+	// Args are the arguments passed to programs and LookupEnv reads
+	// environment variables for them, set it to nil to hide the
+	// environment from untrusted programs
+	Args      []string
+	LookupEnv func(name string) (string, bool)
+	// FileRoot is the only directory programs can open files in, file
+	// access is disabled when it is empty, which is the default
+	FileRoot    string
+	symbolTable *symbol.SymbolTable
+	// Input is buffered once so that nothing is lost between runs
+	input *bufio.Reader
+}
+
+// CreateInterpreter creates a new Interpreter object that reads program
+// input from stdin and writes program output to stdout and diagnostics
+// to stderr
+func CreateInterpreter(stdin io.Reader, stdout, stderr io.Writer) *Interpreter {
+	return &Interpreter{
+		Stdout:      stdout,
+		Stderr:      stderr,
+		MaxDepth:    parser.DEFAULTMAXDEPTH,
+		LookupEnv:   os.LookupEnv,
+		symbolTable: symbol.CreateSymbolTable(),
+		input:       bufio.NewReader(stdin),
+	}
+}
+
+// Result describes how a program finished. ExitCode is 0 when the program
+// runs to the end, the status passed to exit() if it calls it and 1 if it
+// fails with an error. Value is the value of the last statement evaluated
+// when the program runs to the end, and nil otherwise
+type Result struct {
+	ExitCode int
+	Value    symbol.Symbol
+}
+
+/*
+=====================
+Main running function
+=====================
+*/
+
+// Run lexes, parses, type checks and evaluates a program. Variables are kept
+// in the interpreter's symbol table, so a later call can use the variables
+// of an earlier one. Problems are written to Stderr - syntax and type errors
+// stop the program before it runs, and a runtime error is returned as a
+// *symbol.Error. Calling exit() isn't an error, its status is in the Result
+func (interpreter *Interpreter) Run(ctx context.Context, code string) (Result, error) {
+	programParser := parser.CreateParser(lexer.CreateLexer(code))
+	programParser.SetLegacyMode(interpreter.LegacyMode)
+	programParser.SetMaxDepth(interpreter.MaxDepth)
+	program := programParser.ParseProgram()
+
+	for _, warning := range programParser.GetWarnings() {
+		fmt.Fprintln(interpreter.Stderr, warning)
+	}
+
+	// Type check the program before it is evaluated so that
+	// operations on mismatched types are caught up front
+	errors := programParser.GetErrors()
+	if len(errors) == 0 {
+		typeChecker := checker.CreateChecker()
+		for name, value := range interpreter.symbolTable.Table {
+			if variableType := checkerType(value); variableType != checker.UNKNOWN {
+				typeChecker.SetType(name, variableType)
+			}
+		}
+		typeChecker.Check(program)
+		errors = typeChecker.GetErrors()
+	}
+	if len(errors) > 0 {
+		for _, err := range errors {
+			fmt.Fprintln(interpreter.Stderr, err)
+		}
+		return Result{ExitCode: 1}, fmt.Errorf("the program has %d errors", len(errors))
+	}
+
+	interpreter.symbolTable.MaxVariables = interpreter.MaxVariables
+	programEvaluator := evaluator.CreateEvaluator(interpreter.symbolTable)
+	programEvaluator.Output = interpreter.Stdout
+	programEvaluator.SetInput(interpreter.input)
+	programEvaluator.Args = interpreter.Args
+	programEvaluator.LookupEnv = interpreter.LookupEnv
+	programEvaluator.FileRoot = interpreter.FileRoot
+	programEvaluator.CheckedArithmetic = interpreter.CheckedArithmetic
+	programEvaluator.BigIntegers = interpreter.BigIntegers
+	programEvaluator.DisableAssertions = interpreter.DisableAssertions
+	programEvaluator.MaxSteps = interpreter.MaxSteps
+	programEvaluator.Timeout = interpreter.Timeout
+	programEvaluator.MaxOutput = interpreter.MaxOutput
+	evaluated := programEvaluator.Run(ctx, program)
+
+	// Runtime errors stop the program - report where it happened
+	// and which statements were being evaluated at the time
+	if err, ok := evaluated.(*symbol.Error); ok {
+		if err.Exit {
+			return Result{ExitCode: err.ExitCode}, nil
+		}
+		fmt.Fprintln(interpreter.Stderr, err.GetValue())
+		for _, frame := range err.Trace {
+			fmt.Fprintln(interpreter.Stderr, "    "+frame)
+		}
+		return Result{ExitCode: 1}, err
+	}
+	// A program with no statements has no value
+	if evaluated == nil {
+		evaluated = &symbol.Dummy{Value: ""}
+	}
+	return Result{Value: evaluated}, nil
+}
@@ -0,0 +1,151 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+
+	"github.com/sedexdev/go-interpreter/internal/checker"
+	"github.com/sedexdev/go-interpreter/internal/symbol"
+	"github.com/sedexdev/go-interpreter/internal/token"
+)
+
+/*
+====================================
+Setting and saving program variables
+====================================
+*/
+
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// SetVariable stores a variable before a program is run, so the program
+// can use it without assigning it. The name must be a valid identifier
+func (interpreter *Interpreter) SetVariable(name string, value symbol.Symbol) error {
+	if !identifier.MatchString(name) || token.IsKeyword(name) != token.IDENTIFIER {
+		return fmt.Errorf("%q is not a valid variable name", name)
+	}
+	interpreter.symbolTable.MaxVariables = interpreter.MaxVariables
+	if err, ok := interpreter.symbolTable.Set(name, value).(*symbol.Error); ok {
+		return err
+	}
+	return nil
+}
+
+// Define sets a variable from text written the way it would be in C--,
+// e.g. Define("val", "27"). Numbers become ints or doubles, true and
+// false become bools and any other text becomes a string
+func (interpreter *Interpreter) Define(name, text string) error {
+	return interpreter.SetVariable(name, interpreter.parseValue(text))
+}
+
+// LoadVariables sets variables from a JSON object of names and values,
+// e.g. {"val": 27, "name": "collatz"}. Only numbers, bools and strings
+// can be used as values
+func (interpreter *Interpreter) LoadVariables(reader io.Reader) error {
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+	var variables map[string]interface{}
+	if err := decoder.Decode(&variables); err != nil {
+		return fmt.Errorf("couldn't read variables: %v", err)
+	}
+
+	for name, value := range variables {
+		var variable symbol.Symbol
+		switch value := value.(type) {
+		case json.Number:
+			variable = interpreter.parseValue(value.String())
+		case bool:
+			variable = &symbol.Boolean{Value: value}
+		case string:
+			variable = &symbol.String{Value: value}
+		default:
+			return fmt.Errorf("variable %s must be a number, bool or string", name)
+		}
+		if err := interpreter.SetVariable(name, variable); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DumpVariables writes every variable as a JSON object of names and
+// values. Structs are written as objects of their fields
+func (interpreter *Interpreter) DumpVariables(writer io.Writer) error {
+	variables := make(map[string]interface{})
+	for name, value := range interpreter.symbolTable.Table {
+		variables[name] = jsonValue(value)
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(variables)
+}
+
+// Convert text into the C-- value it represents
+func (interpreter *Interpreter) parseValue(text string) symbol.Symbol {
+	if interpreter.BigIntegers {
+		if value, ok := new(big.Int).SetString(text, 10); ok {
+			return &symbol.Integer{Big: value}
+		}
+	} else if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return &symbol.Integer{Value: value}
+	}
+	if value, err := strconv.ParseFloat(text, 64); err == nil {
+		return &symbol.Float{Value: value}
+	}
+	if text == "true" || text == "false" {
+		return &symbol.Boolean{Value: text == "true"}
+	}
+	return &symbol.String{Value: text}
+}
+
+// Convert a C-- value into one that can be written as JSON. Integers are
+// written as JSON numbers however large they are, and doubles that JSON
+// can't represent, such as inf, are written as strings
+func jsonValue(value symbol.Symbol) interface{} {
+	switch value := value.(type) {
+	case *symbol.Integer:
+		return json.Number(value.GetValue())
+	case *symbol.Float:
+		if math.IsInf(value.Value, 0) || math.IsNaN(value.Value) {
+			return value.GetValue()
+		}
+		return json.Number(value.GetValue())
+	case *symbol.Boolean:
+		return value.Value
+	case *symbol.String:
+		return value.Value
+	case *symbol.Struct:
+		fields := make(map[string]interface{})
+		for name, field := range value.Fields {
+			fields[name] = jsonValue(field)
+		}
+		return fields
+	default:
+		return value.GetValue()
+	}
+}
+
+// Get the checker's name for the type of a variable set before a program
+// is run, so that the program can be type checked against it. Structs
+// are left unknown as the checker doesn't have their definitions
+func checkerType(value symbol.Symbol) string {
+	switch value := value.(type) {
+	case *symbol.Integer:
+		if value.Kind != nil {
+			return value.Kind.Name
+		}
+		return checker.INT
+	case *symbol.Float:
+		return checker.DOUBLE
+	case *symbol.Boolean:
+		return checker.BOOL
+	case *symbol.String:
+		return checker.STRING
+	default:
+		return checker.UNKNOWN
+	}
+}
@@ -1,7 +1,9 @@
 package lexer
 
 import (
+	"fmt"
 	"regexp"
+	"unicode/utf8"
 
 	"github.com/sedexdev/go-interpreter/internal/token"
 )
@@ -17,12 +19,24 @@ type Lexer struct {
 	program      string
 	currentIndex int
 	currentChar  byte
+	// Position of the current character, used to give each token
+	// a location in the source code
+	line   int
+	column int
+	// Position of the token currently being read
+	tokenLine   int
+	tokenColumn int
+	tokenIndex  int
+	errors      []string
 }
 
 // CreateLexer creates a new Lexer object
 func CreateLexer(program string) *Lexer {
-	lexer := &Lexer{program: program}
-	lexer.currentChar = program[lexer.currentIndex]
+	lexer := &Lexer{program: program, line: 1, column: 1, errors: []string{}}
+	// An empty program has no characters, so it starts at the end
+	if len(program) > 0 {
+		lexer.currentChar = program[lexer.currentIndex]
+	}
 	return lexer
 }
 
@@ -39,6 +53,9 @@ func (lexer *Lexer) ReadNextToken() token.Token {
 
 	lexer.skipWhitespace()
 	advance := true
+	lexer.tokenLine = lexer.line
+	lexer.tokenColumn = lexer.column
+	lexer.tokenIndex = lexer.currentIndex
 
 	var newToken token.Token
 
@@ -57,7 +74,7 @@ func (lexer *Lexer) ReadNextToken() token.Token {
 			lexer.advance()
 			newToken = makeToken("NOTEQUAL", tok)
 		} else {
-			newToken = makeToken("INVALID", string(lexer.currentChar))
+			newToken = lexer.makeInvalidToken(string(lexer.currentChar), "unexpected character %q", lexer.currentChar)
 		}
 	case '<':
 		if lexer.peek() == '=' {
@@ -81,7 +98,7 @@ func (lexer *Lexer) ReadNextToken() token.Token {
 			lexer.advance()
 			newToken = makeToken("AND", tok)
 		} else {
-			newToken = makeToken("INVALID", string(lexer.currentChar))
+			newToken = lexer.makeInvalidToken(string(lexer.currentChar), "unexpected character %q", lexer.currentChar)
 		}
 	case '|':
 		if lexer.peek() == '|' {
@@ -89,7 +106,7 @@ func (lexer *Lexer) ReadNextToken() token.Token {
 			lexer.advance()
 			newToken = makeToken("OR", tok)
 		} else {
-			newToken = makeToken("INVALID", string(lexer.currentChar))
+			newToken = lexer.makeInvalidToken(string(lexer.currentChar), "unexpected character %q", lexer.currentChar)
 		}
 	case '+':
 		newToken = makeToken("PLUS", string(lexer.currentChar))
@@ -111,29 +128,69 @@ func (lexer *Lexer) ReadNextToken() token.Token {
 		newToken = makeToken("RIGHTCURLYBRACE", string(lexer.currentChar))
 	case ',':
 		newToken = makeToken("COMMA", string(lexer.currentChar))
+	case ';':
+		newToken = makeToken("SEMICOLON", string(lexer.currentChar))
+	case '.':
+		newToken = makeToken("DOT", string(lexer.currentChar))
+	case '"':
+		if str, err := lexer.readQuoted('"'); err == "" {
+			newToken = makeToken("STRING", str)
+		} else {
+			newToken = lexer.makeInvalidToken("\""+str, "%s in string literal", err)
+		}
+	case '\'':
+		newToken = lexer.createCharacter()
 	case 0:
 		newToken = makeToken("END", "nil")
 	default:
 		if digit(lexer.currentChar) {
-			num := lexer.createNumber()
-			newToken = makeToken("INTEGER", num)
+			newToken = lexer.createNumber()
 			advance = false
-		} else if letter(lexer.currentChar) {
+		} else if letter(lexer.currentChar) || lexer.currentChar == '_' {
 			val := lexer.createIdentifier()
 			t := token.IsKeyword(val)
 			newToken = makeToken(t, val)
 			advance = false
 		} else {
-			newToken = makeToken("INVALID", string(lexer.currentChar))
+			newToken = lexer.makeInvalidToken(string(lexer.currentChar), "unexpected character %q", lexer.currentChar)
 		}
 	}
 
 	if advance {
 		lexer.advance()
 	}
+	newToken.Line = lexer.tokenLine
+	newToken.Column = lexer.tokenColumn
+	newToken.Offset = lexer.tokenIndex
 	return newToken
 }
 
+// Source gives the source code between two offsets, such as
+// the offsets of two tokens
+func (lexer *Lexer) Source(start, end int) string {
+	return lexer.program[start:end]
+}
+
+/*
+=================================
+Error handling for lexical errors
+=================================
+*/
+
+// GetErrors will return any errors that arise from invalid characters
+// or malformed literals
+func (lexer *Lexer) GetErrors() []string {
+	return lexer.errors
+}
+
+// Log an error at the position of the token being read and return an
+// INVALID token so the parser can carry on
+func (lexer *Lexer) makeInvalidToken(tokenValue string, format string, a ...interface{}) token.Token {
+	errorMsg := fmt.Sprintf("Lexical error at line %d, column %d: ", lexer.tokenLine, lexer.tokenColumn)
+	lexer.errors = append(lexer.errors, errorMsg+fmt.Sprintf(format, a...))
+	return makeToken("INVALID", tokenValue)
+}
+
 /*
 ======================================
 Matching functions for creating tokens
@@ -158,6 +215,29 @@ func letter(char byte) bool {
 	return letter.MatchString(string(char))
 }
 
+// Match a hexadecimal digit
+func hexDigit(char byte) bool {
+	hex := regexp.MustCompile(`[0-9a-fA-F]`)
+	return hex.MatchString(string(char))
+}
+
+// Match an octal digit
+func octalDigit(char byte) bool {
+	return char >= '0' && char <= '7'
+}
+
+// Get the numeric value of a hexadecimal digit
+func hexValue(char byte) int {
+	switch {
+	case char >= 'a':
+		return int(char-'a') + 10
+	case char >= 'A':
+		return int(char-'A') + 10
+	default:
+		return int(char - '0')
+	}
+}
+
 /*
 ======================
 Lexer helper functions
@@ -166,6 +246,12 @@ Lexer helper functions
 
 // move on to the next character
 func (lexer *Lexer) advance() {
+	if lexer.currentChar == '\n' {
+		lexer.line++
+		lexer.column = 1
+	} else if lexer.currentChar != 0 {
+		lexer.column++
+	}
 	if lexer.currentIndex >= len(lexer.program)-1 {
 		lexer.currentChar = 0
 	} else {
@@ -192,21 +278,155 @@ func (lexer *Lexer) skipWhitespace() {
 // Create an identifier by analysing the current character
 func (lexer *Lexer) createIdentifier() string {
 	word := ""
-	for letter(lexer.currentChar) {
+	// Identifiers start with a letter or underscore and can go
+	// on to contain digits, e.g. uint8_t
+	for letter(lexer.currentChar) || lexer.currentChar == '_' || (word != "" && digit(lexer.currentChar)) {
 		word += string(lexer.currentChar)
 		lexer.advance()
 	}
 	return word
 }
 
-// Create a number by analysing the current character
-func (lexer *Lexer) createNumber() string {
-	number := ""
-	for digit(lexer.currentChar) {
+// Create a number by analysing the current character. A fractional
+// part or an exponent, e.g. 3.14 or 1e-9, makes the number a double
+func (lexer *Lexer) createNumber() token.Token {
+	number := lexer.readDigits()
+	tokenType := token.INTEGER
+
+	// A digit has to follow the point so that it isn't mistaken
+	// for a member access
+	if lexer.currentChar == '.' && digit(lexer.peek()) {
+		lexer.advance()
+		number += "." + lexer.readDigits()
+		tokenType = token.FLOAT
+	}
+	if lexer.currentChar == 'e' || lexer.currentChar == 'E' {
 		number += string(lexer.currentChar)
 		lexer.advance()
+		if lexer.currentChar == '+' || lexer.currentChar == '-' {
+			number += string(lexer.currentChar)
+			lexer.advance()
+		}
+		if !digit(lexer.currentChar) {
+			return lexer.makeInvalidToken(number, "exponent has no digits in %q", number)
+		}
+		number += lexer.readDigits()
+		tokenType = token.FLOAT
+	}
+	return makeToken(tokenType, number)
+}
+
+// Read a run of digits
+func (lexer *Lexer) readDigits() string {
+	digits := ""
+	for digit(lexer.currentChar) {
+		digits += string(lexer.currentChar)
+		lexer.advance()
+	}
+	return digits
+}
+
+// Read the characters up to the closing quote, decoding C escape sequences
+// along the way. Returns a description of the problem if the literal is not
+// terminated on the same line or contains an invalid escape
+func (lexer *Lexer) readQuoted(quote byte) (string, string) {
+	// Bytes are collected in a slice rather than appended with string(char)
+	// so that multi-byte UTF-8 characters in the source are kept intact
+	str := []byte{}
+	err := ""
+	lexer.advance()
+	for lexer.currentChar != quote {
+		if lexer.currentChar == 0 || lexer.currentChar == '\n' {
+			return string(str), "missing terminating " + string(quote)
+		}
+		if lexer.currentChar == '\\' {
+			char, ok := lexer.readEscape()
+			if !ok {
+				// Keep reading up to the closing quote so the rest of the
+				// literal isn't lexed as code
+				err = "invalid escape sequence"
+			}
+			str = append(str, char)
+		} else {
+			str = append(str, lexer.currentChar)
+		}
+		lexer.advance()
+	}
+	return string(str), err
+}
+
+// Create a character literal token. The value of the token is the integer
+// code point of the character, which is what C-- uses to represent it
+func (lexer *Lexer) createCharacter() token.Token {
+	str, err := lexer.readQuoted('\'')
+	if err != "" {
+		return lexer.makeInvalidToken("'"+str, "%s in character literal", err)
+	}
+	if len(str) == 0 {
+		return lexer.makeInvalidToken("''", "empty character literal")
+	}
+	// A single byte covers ASCII and escape sequences, anything longer must
+	// be exactly one UTF-8 encoded character
+	code := rune(str[0])
+	if len(str) > 1 {
+		char, size := utf8.DecodeRuneInString(str)
+		if char == utf8.RuneError || size != len(str) {
+			return lexer.makeInvalidToken("'"+str+"'", "multi-character character literal")
+		}
+		code = char
+	}
+	return makeToken("CHAR", fmt.Sprintf("%d", code))
+}
+
+// Map of single character C escape sequences to the byte they represent
+var escapes = map[byte]byte{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'a':  '\a',
+	'b':  '\b',
+	'f':  '\f',
+	'v':  '\v',
+	'\\': '\\',
+	'\'': '\'',
+	'"':  '"',
+	'?':  '?',
+}
+
+// Decode the escape sequence starting at the current backslash. Supports the
+// single character escapes, hex escapes (\x41) and octal escapes (\101). The
+// lexer is left on the last character of the sequence
+func (lexer *Lexer) readEscape() (byte, bool) {
+	lexer.advance()
+	if char, ok := escapes[lexer.currentChar]; ok {
+		return char, true
+	}
+	if lexer.currentChar == 'x' {
+		if !hexDigit(lexer.peek()) {
+			return 0, false
+		}
+		value := 0
+		for hexDigit(lexer.peek()) {
+			lexer.advance()
+			value = value*16 + hexValue(lexer.currentChar)
+			if value > 255 {
+				return 0, false
+			}
+		}
+		return byte(value), true
+	}
+	if octalDigit(lexer.currentChar) {
+		value := int(lexer.currentChar - '0')
+		for i := 0; i < 2 && octalDigit(lexer.peek()); i++ {
+			lexer.advance()
+			value = value*8 + int(lexer.currentChar-'0')
+		}
+		if value > 255 {
+			return 0, false
+		}
+		return byte(value), true
 	}
-	return number
+	return 0, false
 }
 
 // Create a new token from the token.Token struct
@@ -2,20 +2,30 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 
 	"github.com/sedexdev/go-interpreter/internal/ast"
 	"github.com/sedexdev/go-interpreter/internal/lexer"
 	"github.com/sedexdev/go-interpreter/internal/token"
 )
 
-// Constant denoting no precedence
+// Constants denoting no precedence and the precedence of prefix
+// operators, which bind tighter than any infix operator except "."
 const (
-	NILPRECEDENCE = 0
+	NILPRECEDENCE    = 0
+	PREFIXPRECEDENCE = 7
 )
 
+// DEFAULTMAXDEPTH is how deeply expressions and blocks can be nested
+// unless the limit is changed with SetMaxDepth
+const DEFAULTMAXDEPTH = 500
+
 // Map that binds operators to precedences
 var opPrecedences = map[string]int{
+	"(":  8,
+	".":  8,
 	"*":  6,
 	"/":  6,
 	"%":  6,
@@ -31,7 +41,12 @@ var opPrecedences = map[string]int{
 	"||": 1,
 }
 
-var leftBraceCount int32
+// Type keywords that can begin a declaration
+var typeTokens = map[string]bool{
+	token.INT:    true,
+	token.BOOL:   true,
+	token.DOUBLE: true,
+}
 
 // Function types for token association
 // Includes:
@@ -52,14 +67,31 @@ type Parser struct {
 	// will be called when a token of a particular type is found
 	prefixExpFuncs map[string]prefixExpFunc
 	infixExpFuncs  map[string]infixExpFunc
-	errors         []string
+	// Names declared with const, used to reject later assignments
+	constants map[string]bool
+	// Struct definitions by name, used to recognise struct types
+	structs map[string]*ast.StructStatement
+	// In legacy mode statements can be separated by newlines
+	// instead of semicolons
+	legacy bool
+	// Current nesting depth of expressions and blocks, and the limit
+	// on it. Once the limit is passed the rest of the program is skipped
+	depth    int
+	maxDepth int
+	tooDeep  bool
+	errors   []string
+	warnings []string
 }
 
 // CreateParser creates a new Parser object
 func CreateParser(lexer *lexer.Lexer) *Parser {
 	parser := &Parser{
-		lexer:  lexer,
-		errors: []string{},
+		lexer:     lexer,
+		constants: make(map[string]bool),
+		structs:   make(map[string]*ast.StructStatement),
+		maxDepth:  DEFAULTMAXDEPTH,
+		errors:    []string{},
+		warnings:  []string{},
 	}
 	parser.setTokens()
 	parser.setTokens()
@@ -70,7 +102,13 @@ func CreateParser(lexer *lexer.Lexer) *Parser {
 	parser.prefixExpFuncs = make(map[string]prefixExpFunc)
 	parser.registerPrefixExpFunc(token.IDENTIFIER, parser.parseIdentifier)
 	parser.registerPrefixExpFunc(token.INTEGER, parser.parseInteger)
+	parser.registerPrefixExpFunc(token.FLOAT, parser.parseFloat)
+	parser.registerPrefixExpFunc(token.STRING, parser.parseString)
+	parser.registerPrefixExpFunc(token.CHAR, parser.parseCharacter)
+	parser.registerPrefixExpFunc(token.TRUE, parser.parseBoolean)
+	parser.registerPrefixExpFunc(token.FALSE, parser.parseBoolean)
 	parser.registerPrefixExpFunc("LEFTPARENTHESES", parser.parseBoundExpression)
+	parser.registerPrefixExpFunc("MINUS", parser.parsePrefix)
 
 	// Infix tokens and their associated expression functions
 	parser.infixExpFuncs = make(map[string]infixExpFunc)
@@ -87,10 +125,27 @@ func CreateParser(lexer *lexer.Lexer) *Parser {
 	parser.registerInfixExpFunc("!=", parser.parseInfix)
 	parser.registerInfixExpFunc("&&", parser.parseInfix)
 	parser.registerInfixExpFunc("||", parser.parseInfix)
+	parser.registerInfixExpFunc(".", parser.parseMember)
+	parser.registerInfixExpFunc("(", parser.parseCall)
 
 	return parser
 }
 
+// SetLegacyMode allows statements to be separated by newlines instead of
+// semicolons, as older C-- programs were written. Each missing semicolon
+// is reported by GetWarnings so the program can be migrated
+func (parser *Parser) SetLegacyMode(enabled bool) {
+	parser.legacy = enabled
+}
+
+// SetMaxDepth sets how deeply expressions and blocks can be nested.
+// Parsing is recursive, so the limit stops deeply nested programs
+// from exhausting the Go stack. Zero means there is no limit, like
+// the other quotas
+func (parser *Parser) SetMaxDepth(depth int) {
+	parser.maxDepth = depth
+}
+
 /*
 =================================
 This is the main parsing function
@@ -103,10 +158,9 @@ func (parser *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for parser.currentToken.Type != token.END {
-		statement := parser.parseStatement(false)
+		statement := parser.parseStatement()
 		if statement != nil {
 			program.Statements = append(program.Statements, statement)
-			leftBraceCount = 0
 		}
 		parser.setTokens()
 	}
@@ -119,14 +173,33 @@ Error handling for syntax errors
 ================================
 */
 
-// GetErrors will return any errors that arise from invalid syntax
+// GetErrors will return any errors that arise from invalid syntax,
+// including those found by the lexer while reading tokens
 func (parser *Parser) GetErrors() []string {
-	return parser.errors
+	errors := append([]string{}, parser.lexer.GetErrors()...)
+	return append(errors, parser.errors...)
+}
+
+// GetWarnings will return any warnings about deprecated syntax
+func (parser *Parser) GetWarnings() []string {
+	return parser.warnings
 }
 
-func (parser *Parser) logError(tokenValue string) {
-	errorMsg := fmt.Sprintf("Syntax error, didn't expect %s", tokenValue)
-	parser.errors = append(parser.errors, errorMsg)
+func (parser *Parser) logError(tok token.Token) {
+	parser.logSyntaxError(tok, "didn't expect %s", describeToken(tok))
+}
+
+// Log a syntax error at the position of the given token
+func (parser *Parser) logSyntaxError(tok token.Token, format string, a ...interface{}) {
+	errorMsg := fmt.Sprintf("Syntax error at line %d, column %d: ", tok.Line, tok.Column)
+	parser.errors = append(parser.errors, errorMsg+fmt.Sprintf(format, a...))
+}
+
+// Log an error that is caused by the meaning of valid syntax, such as
+// assigning to a constant, at the position of the given token
+func (parser *Parser) logErrorAt(tok token.Token, format string, a ...interface{}) {
+	errorMsg := fmt.Sprintf("Error at line %d, column %d: ", tok.Line, tok.Column)
+	parser.errors = append(parser.errors, errorMsg+fmt.Sprintf(format, a...))
 }
 
 /*
@@ -143,8 +216,12 @@ func (parser *Parser) checkCurrentPrecedence() int {
 	return NILPRECEDENCE
 }
 
-// Check the precedence of the next token
+// Check the precedence of the next token - string literals are never
+// operators, even if their text matches one
 func (parser *Parser) checkNextPrecedence() int {
+	if parser.nextToken.Type == token.STRING {
+		return NILPRECEDENCE
+	}
 	if result, ok := opPrecedences[parser.nextToken.Value]; ok {
 		return result
 	}
@@ -173,58 +250,87 @@ Parser methods
 ==============
 */
 
-// Parse statements - boolean fromBlock controls flow to avoid identifiers in the
-// middle of expressions being treated as variable declarations, also allows variables
-// to be declared and reassigned within blocks
-func (parser *Parser) parseStatement(fromBlock bool) ast.Statement {
+// Parse statements - if a statement can't be parsed the rest of it is skipped
+// so that one mistake doesn't produce a string of errors for the tokens after it
+func (parser *Parser) parseStatement() ast.Statement {
+	errorCount := len(parser.GetErrors())
+	statement := parser.selectStatement()
+
+	if statement == nil && len(parser.GetErrors()) > errorCount {
+		parser.skipStatement()
+	}
+	return statement
+}
+
+// Choose how to parse a statement based on the token it starts with. Each
+// statement is parsed up to and including its last token
+func (parser *Parser) selectStatement() ast.Statement {
 	switch parser.currentToken.Type {
 	case token.IDENTIFIER:
-		if fromBlock {
-			return parser.parseExpressionStatement()
+		switch {
+		case parser.isStructType(parser.currentToken):
+			return parser.parseTypedDeclaration()
+		case parser.nextToken.Type == "DOT":
+			return parser.parseMemberAssignment()
+		case parser.nextToken.Type == "ASSIGNMENT":
+			return parser.parseVariableDeclaration()
 		}
-		return parser.parseVariableDeclaration()
+		return parser.parseExpressionStatement()
+	case token.STRUCT:
+		return parser.parseStructStatement()
 	case token.IF:
 		return parser.parseIfStatement()
 	case token.WHILE:
 		return parser.parseWhileStatement()
 	case token.PRINT:
 		return parser.parsePrintStatement()
+	case token.READ:
+		return parser.parseReadStatement()
+	case token.ASSERT:
+		return parser.parseAssertStatement()
+	case token.CONST, token.INT, token.BOOL, token.DOUBLE:
+		return parser.parseTypedDeclaration()
+	case "SEMICOLON":
+		// An empty statement
+		return nil
 	default:
 		return parser.parseExpressionStatement()
 	}
 }
 
 // Parse expressions statements
-func (parser *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+func (parser *Parser) parseExpressionStatement() ast.Statement {
 	statement := &ast.ExpressionStatement{Token: parser.currentToken}
-	statement.Expression = parser.parseExpression(NILPRECEDENCE, false)
+	statement.Expression = parser.parseExpression(NILPRECEDENCE)
+	if statement.Expression == nil {
+		return nil
+	}
+	parser.expectTerminator()
 	return statement
 }
 
-// Parse expressions - the fromPrint boolean checks to see if the expression
-// is being created as part of a Print statement. If it isn't, error checking
-// is performed to check for missing operators
-func (parser *Parser) parseExpression(precedence int, fromPrint bool) ast.Expression {
+// Parse expressions - the expression ends at the first token that isn't an
+// operator, the statement being parsed then checks that token is valid
+func (parser *Parser) parseExpression(precedence int) ast.Expression {
+	if !parser.enterNesting() {
+		return nil
+	}
+	defer parser.leaveNesting()
+
 	prefix := parser.prefixExpFuncs[parser.currentToken.Type]
 
 	if prefix == nil {
-		// If this token has no function associated with it, log error and return nil
-		parser.logError(parser.currentToken.Value)
+		// If this token has no function associated with it, log error and return nil.
+		// INVALID tokens have already been reported by the lexer
+		if parser.currentToken.Type != token.INVALID {
+			parser.logError(parser.currentToken)
+		}
 		return nil
 	}
 	// Call the function returned from prefixExpFuncs
 	leftExpression := prefix()
 
-	if !fromPrint {
-		// If the expression did not come from a print statement and is missing an
-		// operator, log an error
-		if parser.nextToken.Type == "INTEGER" || parser.nextToken.Type == "IDENTIFIER" {
-			parser.logError(parser.nextToken.Value)
-			return nil
-		}
-	}
-
-	for precedence < parser.checkNextPrecedence() {
+	for leftExpression != nil && precedence < parser.checkNextPrecedence() {
 		infix := parser.infixExpFuncs[parser.nextToken.Value]
 		if infix == nil {
 			return leftExpression
@@ -236,54 +342,52 @@ func (parser *Parser) parseExpression(precedence int, fromPrint bool) ast.Expres
 	return leftExpression
 }
 
-// Parse a block statement - a block of code after an if, else or while statement
+// Parse a block statement - a block of code between curly braces after an if,
+// else or while statement. The current token is the opening brace, parsing
+// finishes on the closing brace
 func (parser *Parser) parseBlockStatement() *ast.BlockStatement {
-	if parser.currentToken.Type == "LEFTCURLYBRACE" {
-		parser.setTokens()
-		// Setting a count for the number of left curly braces found allows the
-		// program to handle nested block statements
-		leftBraceCount++
+	if !parser.enterNesting() {
+		return nil
 	}
+	defer parser.leaveNesting()
 
 	block := &ast.BlockStatement{Token: parser.currentToken}
 	block.Statements = []ast.Statement{}
+	parser.setTokens()
 
-	returnBlock := false
-
-	for parser.currentToken.Type != "RIGHTCURLYBRACE" && parser.currentToken.Type != token.END {
-		if returnBlock {
-			return block
-		}
-		var statement ast.Statement
-		// Check for ELSE tokens - return this block if ELSE is found
-		if parser.currentToken.Type == "ELSE" {
-			return block
-		}
-		// If a variable declaration is found inside a block, set fromBlock to false in order
-		// to call parseVariableDeclaration() and create a new variable declaration
-		if parser.currentToken.Type == "IDENTIFIER" && parser.nextToken.Type == "ASSIGNMENT" {
-			statement = parser.parseStatement(false)
-			parser.setTokens()
-		} else {
-			statement = parser.parseStatement(true)
+	for parser.currentToken.Type != "RIGHTCURLYBRACE" {
+		if parser.currentToken.Type == token.END {
+			// Blocks that were cut short by nesting too deeply
+			// have already been reported
+			if !parser.tooDeep {
+				parser.logSyntaxError(parser.currentToken, "missing } to close block")
+			}
+			return nil
 		}
+		statement := parser.parseStatement()
 		if statement != nil {
 			block.Statements = append(block.Statements, statement)
-			// If this is the end of an inner block, shift tokens and reduce leftBraceCount,
-			// then set returnBlock to true so that this block gets returned as a part of the
-			// outer block
-			if parser.currentToken.Type == "RIGHTCURLYBRACE" && leftBraceCount > 1 {
-				parser.setTokens()
-				leftBraceCount--
-				returnBlock = true
-			}
-		} else {
-			return block
 		}
+		parser.setTokens()
 	}
 	return block
 }
 
+// Parse prefix expressions such as -x
+func (parser *Parser) parsePrefix() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token:    parser.currentToken,
+		Operator: parser.currentToken.Value,
+	}
+
+	parser.setTokens()
+	expression.Right = parser.parseExpression(PREFIXPRECEDENCE)
+	if expression.Right == nil {
+		return nil
+	}
+	return expression
+}
+
 // Parse infix expressions
 func (parser *Parser) parseInfix(left ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{
@@ -294,50 +398,62 @@ func (parser *Parser) parseInfix(left ast.Expression) ast.Expression {
 
 	precedence := parser.checkCurrentPrecedence()
 	parser.setTokens()
-	expression.Right = parser.parseExpression(precedence, false)
+	expression.Right = parser.parseExpression(precedence)
+	if expression.Right == nil {
+		return nil
+	}
 	return expression
 }
 
 // Parse an expression surrounded by ordering parentheses
 func (parser *Parser) parseBoundExpression() ast.Expression {
 	parser.setTokens()
-	expression := parser.parseExpression(NILPRECEDENCE, false)
+	expression := parser.parseExpression(NILPRECEDENCE)
 
-	if !parser.expectNext("RIGHTPARENTHESES") {
-		parser.logError(parser.nextToken.Value)
+	if expression == nil || !parser.expectNext("RIGHTPARENTHESES") {
 		return nil
 	}
 	return expression
 }
 
-// Parse an if statement
+// Parse an if statement - "else if" is parsed as an else
+// branch that holds a single if statement
 func (parser *Parser) parseIfStatement() ast.Statement {
 	statement := &ast.IfStatement{Token: parser.currentToken}
 
-	if !parser.expectNext("LEFTPARENTHESES") {
-		parser.logError(parser.nextToken.Value)
+	statement.Condition = parser.parseCondition()
+	if statement.Condition == nil || !parser.expectNext("LEFTCURLYBRACE") {
 		return nil
 	}
 
-	parser.setTokens()
-	statement.Condition = parser.parseExpression(NILPRECEDENCE, false)
-
-	if statement.Condition == nil {
-		parser.logError("condition of statement to be nil")
+	statement.FirstBranch = parser.parseBlockStatement()
+	if statement.FirstBranch == nil {
 		return nil
 	}
 
-	if !parser.expectNext("RIGHTPARENTHESES") {
-		parser.logError(parser.nextToken.Value)
-		return nil
+	if parser.nextToken.Type != token.ELSE {
+		return statement
 	}
-
 	parser.setTokens()
-	statement.FirstBranch = parser.parseBlockStatement()
 
-	if parser.currentToken.Type == "ELSE" {
+	if parser.nextToken.Type == token.IF {
 		parser.setTokens()
-		statement.SecondBranch = parser.parseBlockStatement()
+		branch := &ast.BlockStatement{Token: parser.currentToken}
+		elseIf := parser.parseIfStatement()
+		if elseIf == nil {
+			return nil
+		}
+		branch.Statements = []ast.Statement{elseIf}
+		statement.SecondBranch = branch
+		return statement
+	}
+
+	if !parser.expectNext("LEFTCURLYBRACE") {
+		return nil
+	}
+	statement.SecondBranch = parser.parseBlockStatement()
+	if statement.SecondBranch == nil {
+		return nil
 	}
 	return statement
 }
@@ -346,32 +462,43 @@ func (parser *Parser) parseIfStatement() ast.Statement {
 func (parser *Parser) parseWhileStatement() ast.Statement {
 	statement := &ast.WhileStatement{Token: parser.currentToken}
 
-	if !parser.expectNext("LEFTPARENTHESES") {
-		parser.logError(parser.nextToken.Value)
+	statement.Condition = parser.parseCondition()
+	if statement.Condition == nil || !parser.expectNext("LEFTCURLYBRACE") {
 		return nil
 	}
 
-	parser.setTokens()
-	statement.Condition = parser.parseExpression(NILPRECEDENCE, false)
-
-	if statement.Condition == nil {
-		parser.logError("condition of statement to be nil")
+	statement.Loop = parser.parseBlockStatement()
+	if statement.Loop == nil {
 		return nil
 	}
+	return statement
+}
 
-	if !parser.expectNext("RIGHTPARENTHESES") {
-		parser.logError(parser.nextToken.Value)
+// Parse the condition of an if statement or while loop, which
+// must be surrounded by parentheses
+func (parser *Parser) parseCondition() ast.Expression {
+	if !parser.expectNext("LEFTPARENTHESES") {
 		return nil
 	}
 
 	parser.setTokens()
-	statement.Loop = parser.parseBlockStatement()
-	return statement
+	condition := parser.parseExpression(NILPRECEDENCE)
+
+	if condition == nil || !parser.expectNext("RIGHTPARENTHESES") {
+		return nil
+	}
+	return condition
 }
 
 // Parse print statement
 func (parser *Parser) parsePrintStatement() ast.Statement {
-	statement := &ast.PrintStatement{Token: parser.currentToken}
+	statement := &ast.PrintStatement{Token: parser.currentToken, Newline: parser.currentToken.Value != "print_inline"}
+
+	// A print with no values just ends the line
+	if parser.nextToken.Type == "SEMICOLON" {
+		parser.setTokens()
+		return statement
+	}
 
 	// In order to recursively call a function declared inside another function,
 	// the function must be defined as a closure
@@ -379,7 +506,7 @@ func (parser *Parser) parsePrintStatement() ast.Statement {
 
 	setValues = func(values []ast.Expression) []ast.Expression {
 		parser.setTokens()
-		values = append(values, parser.parseExpression(NILPRECEDENCE, true))
+		values = append(values, parser.parseExpression(NILPRECEDENCE))
 
 		if parser.nextToken.Type == "COMMA" {
 			parser.setTokens()
@@ -389,27 +516,263 @@ func (parser *Parser) parsePrintStatement() ast.Statement {
 	}
 	// Call setValues to start recursively adding values to statement.Values
 	statement.Values = setValues(statement.Values)
+	for _, value := range statement.Values {
+		if value == nil {
+			return nil
+		}
+	}
+
+	parser.expectTerminator()
+	return statement
+}
+
+// Parse a read statement - one or more variables separated by commas
+func (parser *Parser) parseReadStatement() ast.Statement {
+	statement := &ast.ReadStatement{Token: parser.currentToken}
+
+	for {
+		if !parser.expectNext(token.IDENTIFIER) {
+			return nil
+		}
+		name := &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Value}
+		if parser.constants[name.Value] {
+			parser.logErrorAt(parser.currentToken, "cannot read into constant %s", name.Value)
+		}
+		statement.Names = append(statement.Names, name)
+
+		if parser.nextToken.Type != "COMMA" {
+			break
+		}
+		parser.setTokens()
+	}
+	parser.expectTerminator()
+	return statement
+}
+
+// Parse an assert statement - a condition and an optional message in
+// parentheses. The source code of the condition is kept for the error
+// reported when it fails
+func (parser *Parser) parseAssertStatement() ast.Statement {
+	statement := &ast.AssertStatement{Token: parser.currentToken}
+	if !parser.expectNext("LEFTPARENTHESES") {
+		return nil
+	}
+
 	parser.setTokens()
+	statement.Start = parser.currentToken
+	statement.Condition = parser.parseExpression(NILPRECEDENCE)
+	if statement.Condition == nil {
+		return nil
+	}
+	source := parser.lexer.Source(statement.Start.Offset, parser.nextToken.Offset)
+	statement.Source = strings.TrimSpace(source)
 
+	if parser.nextToken.Type == "COMMA" {
+		parser.setTokens()
+		parser.setTokens()
+		statement.Message = parser.parseExpression(NILPRECEDENCE)
+		if statement.Message == nil {
+			return nil
+		}
+	}
+	if !parser.expectNext("RIGHTPARENTHESES") {
+		return nil
+	}
+	parser.expectTerminator()
 	return statement
 }
 
 // Parse variable declarations
-func (parser *Parser) parseVariableDeclaration() *ast.VariableStatement {
+func (parser *Parser) parseVariableDeclaration() ast.Statement {
 	varStatement := &ast.VariableStatement{Token: parser.currentToken}
 	varStatement.Name = &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Value}
 
+	if parser.constants[varStatement.Name.Value] {
+		parser.logErrorAt(parser.currentToken, "cannot assign to constant %s", varStatement.Name.Value)
+	}
+
 	if !parser.expectNext("ASSIGNMENT") {
-		parser.logError(parser.nextToken.Value)
 		return nil
 	}
 
 	parser.setTokens()
-	varStatement.Value = parser.parseExpression(NILPRECEDENCE, false)
+	varStatement.Value = parser.parseExpression(NILPRECEDENCE)
+	if varStatement.Value == nil {
+		return nil
+	}
+	parser.expectTerminator()
+	return varStatement
+}
 
+// Parse declarations that name a type, optionally marked as const,
+// e.g. "const int LIMIT = 100;"
+func (parser *Parser) parseTypedDeclaration() ast.Statement {
+	varStatement := &ast.VariableStatement{Token: parser.currentToken}
+
+	if parser.currentToken.Type == token.CONST {
+		varStatement.Constant = true
+		parser.setTokens()
+	}
+
+	if !typeTokens[parser.currentToken.Type] && !parser.isStructType(parser.currentToken) {
+		parser.logError(parser.currentToken)
+		return nil
+	}
+	varStatement.Type = parser.currentToken.Value
+	varStatement.Struct = parser.structs[varStatement.Type]
+
+	if !parser.expectNext(token.IDENTIFIER) {
+		return nil
+	}
+	varStatement.Name = &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Value}
+
+	if parser.constants[varStatement.Name.Value] {
+		parser.logErrorAt(parser.currentToken, "redeclaration of constant %s", varStatement.Name.Value)
+	}
+	if varStatement.Constant {
+		parser.constants[varStatement.Name.Value] = true
+	}
+
+	// Variables declared without an initialiser get the zero value of
+	// their type, constants must be initialised where they are declared
+	if !varStatement.Constant && parser.nextToken.Type != "ASSIGNMENT" {
+		parser.expectTerminator()
+		return varStatement
+	}
+	if !parser.expectNext("ASSIGNMENT") {
+		return nil
+	}
+
+	parser.setTokens()
+	varStatement.Value = parser.parseExpression(NILPRECEDENCE)
+	if varStatement.Value == nil {
+		return nil
+	}
+	parser.expectTerminator()
 	return varStatement
 }
 
+// Parse a struct definition, e.g. "struct Point { int x; int y; };"
+func (parser *Parser) parseStructStatement() ast.Statement {
+	statement := &ast.StructStatement{Token: parser.currentToken}
+
+	if !parser.expectNext(token.IDENTIFIER) {
+		return nil
+	}
+	statement.Name = &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Value}
+
+	if !parser.expectNext("LEFTCURLYBRACE") {
+		return nil
+	}
+
+	fieldNames := make(map[string]bool)
+	for parser.nextToken.Type != "RIGHTCURLYBRACE" {
+		parser.setTokens()
+		field := &ast.StructField{Token: parser.currentToken, Type: parser.currentToken.Value}
+
+		// Fields can have any type that is already known, including other structs
+		if !typeTokens[parser.currentToken.Type] && !parser.isStructType(parser.currentToken) {
+			parser.logError(parser.currentToken)
+			return nil
+		}
+		field.Struct = parser.structs[field.Type]
+
+		if !parser.expectNext(token.IDENTIFIER) {
+			return nil
+		}
+		field.Name = &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Value}
+
+		if fieldNames[field.Name.Value] {
+			parser.logErrorAt(parser.currentToken, "duplicate field %s in struct %s", field.Name.Value, statement.Name.Value)
+		}
+		fieldNames[field.Name.Value] = true
+
+		if !parser.expectNext("SEMICOLON") {
+			return nil
+		}
+		statement.Fields = append(statement.Fields, field)
+	}
+
+	parser.setTokens()
+	parser.expectTerminator()
+
+	if _, ok := parser.structs[statement.Name.Value]; ok {
+		parser.logErrorAt(statement.Name.Token, "redefinition of struct %s", statement.Name.Value)
+	}
+	parser.structs[statement.Name.Value] = statement
+	return statement
+}
+
+// Parse an assignment to a struct field, e.g. "p.x = 5"
+func (parser *Parser) parseMemberAssignment() ast.Statement {
+	statement := &ast.MemberAssignStatement{Token: parser.currentToken}
+
+	if parser.constants[parser.currentToken.Value] {
+		parser.logErrorAt(parser.currentToken, "cannot assign to constant %s", parser.currentToken.Value)
+	}
+
+	target, ok := parser.parseExpression(NILPRECEDENCE).(*ast.MemberExpression)
+	if !ok {
+		return nil
+	}
+	statement.Target = target
+
+	if !parser.expectNext("ASSIGNMENT") {
+		return nil
+	}
+
+	parser.setTokens()
+	statement.Value = parser.parseExpression(NILPRECEDENCE)
+	if statement.Value == nil {
+		return nil
+	}
+	parser.expectTerminator()
+	return statement
+}
+
+// Parse access to a struct field - the left expression is the struct
+func (parser *Parser) parseMember(object ast.Expression) ast.Expression {
+	expression := &ast.MemberExpression{Token: parser.currentToken, Object: object}
+
+	if !parser.expectNext(token.IDENTIFIER) {
+		return nil
+	}
+	expression.Field = &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Value}
+	return expression
+}
+
+// Parse a function call - the left expression is the name of the function
+// and the current token is the opening parenthesis of the arguments
+func (parser *Parser) parseCall(function ast.Expression) ast.Expression {
+	name, ok := function.(*ast.Identifier)
+	if !ok {
+		parser.logSyntaxError(parser.currentToken, "only functions can be called")
+		return nil
+	}
+	call := &ast.CallExpression{Token: name.Token, Function: name, Arguments: []ast.Expression{}}
+
+	if parser.nextToken.Type == "RIGHTPARENTHESES" {
+		parser.setTokens()
+		return call
+	}
+	for {
+		parser.setTokens()
+		argument := parser.parseExpression(NILPRECEDENCE)
+		if argument == nil {
+			return nil
+		}
+		call.Arguments = append(call.Arguments, argument)
+		if parser.nextToken.Type != "COMMA" {
+			break
+		}
+		parser.setTokens()
+	}
+	if !parser.expectNext("RIGHTPARENTHESES") {
+		return nil
+	}
+	return call
+}
+
 // Parse identifiers
 func (parser *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Value}
@@ -422,20 +785,121 @@ func (parser *Parser) parseInteger() ast.Expression {
 	value, ok := strconv.ParseInt(parser.currentToken.Value, 0, 64)
 
 	if ok != nil {
-		errorMsg := fmt.Sprintf("Unable to parse %q as an integer", parser.currentToken.Value)
-		parser.errors = append(parser.errors, errorMsg)
-		return nil
+		// Literals of any length are accepted, whether they can be
+		// used depends on the integer mode they are evaluated in
+		bigValue, isBig := new(big.Int).SetString(parser.currentToken.Value, 0)
+		if !isBig {
+			parser.logErrorAt(parser.currentToken, "unable to parse %q as an integer", parser.currentToken.Value)
+			return nil
+		}
+		integer.Big = bigValue
+		return integer
 	}
 	integer.Value = value
 	return integer
 }
 
+// Parse doubles
+func (parser *Parser) parseFloat() ast.Expression {
+	value, err := strconv.ParseFloat(parser.currentToken.Value, 64)
+	if err != nil {
+		parser.logErrorAt(parser.currentToken, "%q is out of range for a double", parser.currentToken.Value)
+		return nil
+	}
+	return &ast.Float{Token: parser.currentToken, Value: value}
+}
+
+// Parse strings - escape sequences have already been decoded by the lexer
+func (parser *Parser) parseString() ast.Expression {
+	return &ast.String{Token: parser.currentToken, Value: parser.currentToken.Value}
+}
+
+// Parse the boolean literals true and false
+func (parser *Parser) parseBoolean() ast.Expression {
+	return &ast.Boolean{Token: parser.currentToken, Value: parser.currentToken.Type == token.TRUE}
+}
+
+// Parse character literals - the lexer has already converted the
+// character into its integer code point
+func (parser *Parser) parseCharacter() ast.Expression {
+	value, _ := strconv.ParseInt(parser.currentToken.Value, 10, 64)
+	return &ast.Integer{Token: parser.currentToken, Value: value}
+}
+
 /*
 =========================================================================
 Helper methods on the Parser for parsing tokens and creating AST nodes
 =========================================================================
 */
 
+// Check whether a token names a struct that has been defined
+func (parser *Parser) isStructType(tok token.Token) bool {
+	_, ok := parser.structs[tok.Value]
+	return tok.Type == token.IDENTIFIER && ok
+}
+
+// Check that the statement just parsed is terminated by a semicolon. In legacy
+// mode a statement that ends at a line break, a closing brace or the end of
+// the program is also accepted, and a warning is logged to help migrate it.
+// A missing semicolon doesn't stop the statement itself from being used, so
+// the tokens after it are parsed as normal
+func (parser *Parser) expectTerminator() {
+	if parser.nextToken.Type == "SEMICOLON" {
+		parser.setTokens()
+		return
+	}
+
+	if parser.legacy && (parser.nextToken.Line > parser.currentToken.Line ||
+		parser.nextToken.Type == "RIGHTCURLYBRACE" || parser.nextToken.Type == token.END) {
+		warning := fmt.Sprintf("Warning at line %d, column %d: missing ';' after statement, "+
+			"newline-separated statements are deprecated", parser.currentToken.Line, parser.currentToken.Column)
+		parser.warnings = append(parser.warnings, warning)
+		return
+	}
+
+	parser.logSyntaxError(parser.nextToken, "expected ';' before %s", describeToken(parser.nextToken))
+}
+
+// Skip the rest of a statement that couldn't be parsed, stopping at its
+// semicolon or before the closing brace of the block it is in
+func (parser *Parser) skipStatement() {
+	for parser.currentToken.Type != "SEMICOLON" && parser.nextToken.Type != "RIGHTCURLYBRACE" &&
+		parser.nextToken.Type != token.END {
+		parser.setTokens()
+	}
+}
+
+// Go one level deeper into an expression or block. If this passes the
+// nesting limit an error is logged and the rest of the program is skipped,
+// so that none of the levels it is nested in report errors of their own
+func (parser *Parser) enterNesting() bool {
+	parser.depth++
+	if parser.maxDepth <= 0 || parser.depth <= parser.maxDepth {
+		return true
+	}
+	parser.depth--
+	if !parser.tooDeep {
+		parser.logSyntaxError(parser.currentToken, "nesting is deeper than the limit of %d", parser.maxDepth)
+		parser.tooDeep = true
+	}
+	for parser.nextToken.Type != token.END {
+		parser.setTokens()
+	}
+	return false
+}
+
+func (parser *Parser) leaveNesting() {
+	parser.depth--
+}
+
+// Describe a token for use in an error message
+func describeToken(tok token.Token) string {
+	if tok.Type == token.END {
+		return "end of program"
+	}
+	return tok.Value
+}
+
 func (parser *Parser) setTokens() {
 	parser.currentToken = parser.nextToken
 	parser.nextToken = parser.lexer.ReadNextToken()
@@ -446,6 +910,6 @@ func (parser *Parser) expectNext(tokenType string) bool {
 		parser.setTokens()
 		return true
 	}
-	parser.logError(parser.nextToken.Value)
+	parser.logError(parser.nextToken)
 	return false
 }
@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sedexdev/go-interpreter/internal/lexer"
+)
+
+func TestMaxDepth(t *testing.T) {
+	code := "print ((((1))));"
+	tests := []struct {
+		name     string
+		maxDepth int
+		wantErr  bool
+	}{
+		{"default limit", DEFAULTMAXDEPTH, false},
+		{"zero means no limit", 0, false},
+		{"negative means no limit", -1, false},
+		{"limit reached", 3, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			programParser := CreateParser(lexer.CreateLexer(code))
+			programParser.SetMaxDepth(test.maxDepth)
+			programParser.ParseProgram()
+			errors := programParser.GetErrors()
+			if test.wantErr {
+				if len(errors) != 1 || !strings.Contains(errors[0], "nesting is deeper than the limit of 3") {
+					t.Errorf("got errors %v, want one nesting error", errors)
+				}
+			} else if len(errors) > 0 {
+				t.Errorf("unexpected errors: %v", errors)
+			}
+		})
+	}
+}
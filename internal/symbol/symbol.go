@@ -1,6 +1,12 @@
 package symbol
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
 
 // Symbol is for creating symbols that represent
 // values when evaluating the AST
@@ -9,9 +15,13 @@ type Symbol interface {
 	GetValue() string
 }
 
-// Integer symbol
+// Integer symbol - in big integer mode the value is held
+// in Big and Value is unused. Kind is set for the fixed-width
+// types, plain ints have no kind
 type Integer struct {
 	Value int64
+	Big   *big.Int
+	Kind  *IntegerKind
 }
 
 // GetType returns the INTEGER symbol type
@@ -22,9 +32,150 @@ func (integer *Integer) GetType() string {
 // GetValue returns a string representation of the
 // value of an integer
 func (integer *Integer) GetValue() string {
+	if integer.Big != nil {
+		return integer.Big.String()
+	}
+	if integer.Kind != nil && integer.Kind.Unsigned {
+		return strconv.FormatUint(uint64(integer.Value), 10)
+	}
 	return fmt.Sprintf("%d", integer.Value)
 }
 
+// ToBig returns the value of the integer as a big.Int
+func (integer *Integer) ToBig() *big.Int {
+	if integer.Big != nil {
+		return integer.Big
+	}
+	if integer.Kind != nil && integer.Kind.Unsigned {
+		return new(big.Int).SetUint64(uint64(integer.Value))
+	}
+	return big.NewInt(integer.Value)
+}
+
+// IntegerKind describes a fixed-width integer type. Values of every
+// kind are held in an int64 - unsigned 64-bit values keep their bits
+// and are only read as unsigned when they are used
+type IntegerKind struct {
+	Name     string
+	Bits     uint
+	Unsigned bool
+}
+
+// IntegerKinds maps the names of the fixed-width types to their kinds.
+// int64_t is the same type as int, so it has no kind
+var IntegerKinds = map[string]*IntegerKind{
+	"int8_t":   {Name: "int8_t", Bits: 8},
+	"int16_t":  {Name: "int16_t", Bits: 16},
+	"int32_t":  {Name: "int32_t", Bits: 32},
+	"uint8_t":  {Name: "uint8_t", Bits: 8, Unsigned: true},
+	"uint16_t": {Name: "uint16_t", Bits: 16, Unsigned: true},
+	"uint32_t": {Name: "uint32_t", Bits: 32, Unsigned: true},
+	"uint64_t": {Name: "uint64_t", Bits: 64, Unsigned: true},
+}
+
+// Wrap truncates a value to the width of the kind, the way C++
+// converts a value to a narrower integer type
+func (kind *IntegerKind) Wrap(value int64) int64 {
+	shift := 64 - kind.Bits
+	if kind.Unsigned {
+		return int64(uint64(value) << shift >> shift)
+	}
+	return value << shift >> shift
+}
+
+// Float symbol for values of type double
+type Float struct {
+	Value float64
+}
+
+// GetType returns the FLOAT symbol type
+func (float *Float) GetType() string {
+	return "FLOAT"
+}
+
+// GetValue returns the shortest representation of the double that
+// parses back to exactly the same value. Infinities and NaN are
+// written the way C++ prints them
+func (float *Float) GetValue() string {
+	switch {
+	case math.IsInf(float.Value, 1):
+		return "inf"
+	case math.IsInf(float.Value, -1):
+		return "-inf"
+	case math.IsNaN(float.Value):
+		return "nan"
+	}
+	return strconv.FormatFloat(float.Value, 'g', -1, 64)
+}
+
+// Boolean symbol
+type Boolean struct {
+	Value bool
+}
+
+// GetType returns the BOOLEAN symbol type
+func (boolean *Boolean) GetType() string {
+	return "BOOLEAN"
+}
+
+// GetValue returns 1 or 0, the way C++ prints a bool
+func (boolean *Boolean) GetValue() string {
+	if boolean.Value {
+		return "1"
+	}
+	return "0"
+}
+
+// String symbol
+type String struct {
+	Value string
+}
+
+// GetType returns the STRING symbol type
+func (str *String) GetType() string {
+	return "STRING"
+}
+
+// GetValue returns the string itself
+func (str *String) GetValue() string {
+	return str.Value
+}
+
+// Struct symbol holds the values of the fields of a struct.
+// FieldNames keeps the fields in the order they were declared
+type Struct struct {
+	TypeName   string
+	FieldNames []string
+	Fields     map[string]Symbol
+}
+
+// GetType returns the STRUCT symbol type
+func (structure *Struct) GetType() string {
+	return "STRUCT"
+}
+
+// GetValue returns the struct name followed by its field values
+func (structure *Struct) GetValue() string {
+	values := []string{}
+	for _, name := range structure.FieldNames {
+		values = append(values, name+": "+structure.Fields[name].GetValue())
+	}
+	return structure.TypeName + "{" + strings.Join(values, ", ") + "}"
+}
+
+// Copy returns a deep copy of the struct, giving structs the
+// value semantics they have in C++
+func (structure *Struct) Copy() *Struct {
+	fields := make(map[string]Symbol)
+	for name, value := range structure.Fields {
+		if inner, ok := value.(*Struct); ok {
+			value = inner.Copy()
+		}
+		fields[name] = value
+	}
+	return &Struct{TypeName: structure.TypeName, FieldNames: structure.FieldNames, Fields: fields}
+}
+
 // Dummy symbol for when a function has no value to return
 type Dummy struct {
 	Value string
@@ -37,19 +188,24 @@ func (dummy *Dummy) GetType() string {
 
 // GetValue returns an empty string
 func (dummy *Dummy) GetValue() string {
-	return fmt.Sprintf(dummy.Value)
+	return dummy.Value
 }
 
 // SymbolTable for storing variables at evaluation - uses
-// a map of key value pairs that can be accessed and updated
+// a map of key value pairs that can be accessed and updated.
+// Names declared with const are recorded as read-only
 type SymbolTable struct {
-	Table map[string]Symbol
+	Table     map[string]Symbol
+	constants map[string]bool
+	// MaxVariables limits how many variables can be stored,
+	// zero means there is no limit
+	MaxVariables int
 }
 
 // CreateSymbolTable creates a new instance of SymbolTable
 func CreateSymbolTable() *SymbolTable {
 	table := make(map[string]Symbol)
-	return &SymbolTable{Table: table}
+	return &SymbolTable{Table: table, constants: make(map[string]bool)}
 }
 
 // Get will get a value from a SymbolTable instance
@@ -58,15 +214,50 @@ func (symbolTable *SymbolTable) Get(identifier string) (Symbol, bool) {
 	return table, ok
 }
 
-// Set will set a value in a SymbolTable instance
+// Set will set a value in a SymbolTable instance. Adding a new
+// variable to a full table returns a limit exceeded Error instead
 func (symbolTable *SymbolTable) Set(identifier string, value Symbol) Symbol {
+	_, exists := symbolTable.Table[identifier]
+	if !exists && symbolTable.MaxVariables > 0 && len(symbolTable.Table) >= symbolTable.MaxVariables {
+		return &Error{
+			Message:       fmt.Sprintf("the variable limit of %d was reached", symbolTable.MaxVariables),
+			LimitExceeded: true,
+		}
+	}
 	symbolTable.Table[identifier] = value
 	return value
 }
 
-// Error symbol stores errors that occur in evaluation
+// SetConstant will set a value in a SymbolTable instance
+// and mark the binding as read-only
+func (symbolTable *SymbolTable) SetConstant(identifier string, value Symbol) Symbol {
+	result := symbolTable.Set(identifier, value)
+	if _, ok := result.(*Error); !ok {
+		symbolTable.constants[identifier] = true
+	}
+	return result
+}
+
+// IsConstant checks whether an identifier was declared as a constant
+func (symbolTable *SymbolTable) IsConstant(identifier string) bool {
+	return symbolTable.constants[identifier]
+}
+
+// Error symbol stores errors that occur in evaluation. Line and
+// Column locate the error in the source code and Trace lists the
+// statements that were being evaluated, innermost first.
+// LimitExceeded marks a program that was stopped by one of the
+// evaluator's execution limits rather than by an error of its own.
+// Exit marks a program that called exit(), which unwinds the same
+// way as an error but isn't one - ExitCode is the status it passed
 type Error struct {
-	Message string
+	Message       string
+	Line          int
+	Column        int
+	Trace         []string
+	LimitExceeded bool
+	Exit          bool
+	ExitCode      int
 }
 
 // GetType returns the ERROR type
@@ -74,7 +265,20 @@ func (err *Error) GetType() string {
 	return "ERROR"
 }
 
-// GetValue returns the error message
+// Error returns the same text as GetValue, so that an Error
+// can be used as a Go error
+func (err *Error) Error() string {
+	return err.GetValue()
+}
+
+// GetValue returns the error message and its location
 func (err *Error) GetValue() string {
-	return "ERROR: " + err.Message
+	kind := "Runtime error"
+	if err.LimitExceeded {
+		kind = "Limit exceeded"
+	}
+	if err.Line == 0 {
+		return kind + ": " + err.Message
+	}
+	return fmt.Sprintf("%s at line %d, column %d: %s", kind, err.Line, err.Column, err.Message)
 }
@@ -6,24 +6,64 @@ const (
 	END        = "END"
 	IDENTIFIER = "IDENTIFIER"
 	INTEGER    = "INTEGER"
+	FLOAT      = "FLOAT"
+	STRING     = "STRING"
+	CHAR       = "CHAR"
 	WHILE      = "WHILE"
 	IF         = "IF"
 	ELSE       = "ELSE"
 	PRINT      = "PRINT"
+	READ       = "READ"
+	ASSERT     = "ASSERT"
+	CONST      = "CONST"
+	INT        = "INT"
+	BOOL       = "BOOL"
+	DOUBLE     = "DOUBLE"
+	TRUE       = "TRUE"
+	FALSE      = "FALSE"
+	STRUCT     = "STRUCT"
 )
 
-// Token - Creates a token struct
+// Token - Creates a token struct. Line and Column give
+// the position of the first character of the token, and
+// Offset gives its index in the source code
 type Token struct {
-	Type  string
-	Value string
+	Type   string
+	Value  string
+	Line   int
+	Column int
+	Offset int
 }
 
 // Map for matching keywords
 var keywords = map[string]string{
-	"while": WHILE,
-	"if":    IF,
-	"else":  ELSE,
-	"print": PRINT,
+	"while":  WHILE,
+	"if":     IF,
+	"else":   ELSE,
+	"print":  PRINT,
+	"read":   READ,
+	"assert": ASSERT,
+	"const":  CONST,
+	"int":    INT,
+	"bool":   BOOL,
+	"double": DOUBLE,
+	"true":   TRUE,
+	"false":  FALSE,
+	"struct": STRUCT,
+	// println is the same as print, print_inline
+	// doesn't end the line
+	"println":      PRINT,
+	"print_inline": PRINT,
+	// The fixed-width integer types share the INT token,
+	// the value of the token gives the type
+	"int8_t":   INT,
+	"int16_t":  INT,
+	"int32_t":  INT,
+	"int64_t":  INT,
+	"uint8_t":  INT,
+	"uint16_t": INT,
+	"uint32_t": INT,
+	"uint64_t": INT,
 }
 
 // IsKeyword looks in the keywords map to see
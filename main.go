@@ -1,34 +1,174 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 
-	"github.com/sedexdev/go-interpreter/internal/evaluator"
-	"github.com/sedexdev/go-interpreter/internal/lexer"
+	"github.com/sedexdev/go-interpreter/internal/interpreter"
 	"github.com/sedexdev/go-interpreter/internal/parser"
-	"github.com/sedexdev/go-interpreter/internal/symbol"
 	"github.com/sedexdev/go-interpreter/internal/testcode"
 )
 
+// defines collects the name=value pairs given with repeated -D flags
+type defines []string
+
+func (values *defines) String() string {
+	return strings.Join(*values, ",")
+}
+
+func (values *defines) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("expected name=value, got %q", value)
+	}
+	*values = append(*values, value)
+	return nil
+}
+
+// Parse the command line. Flags can come before "run" as well as after
+// it, and run is true when the arguments after "run" - the program's name
+// followed by its arguments - are returned. Any other argument is an error
+func parseCommandLine(flags *flag.FlagSet, args []string) (run bool, runArgs []string, err error) {
+	if err := flags.Parse(args); err != nil {
+		return false, nil, err
+	}
+	if flags.NArg() == 0 {
+		return false, nil, nil
+	}
+	if flags.Arg(0) != "run" {
+		return false, nil, fmt.Errorf("unexpected argument %q, use \"run program.cmm\" to run a program from a file", flags.Arg(0))
+	}
+	runArgs, err = parseRunArgs(flags, flags.Args()[1:])
+	return err == nil, runArgs, err
+}
+
+// Parse the arguments after "run", which can mix flags with the program's
+// name and arguments. Anything after "--" is passed to the program as is
+func parseRunArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for len(args) > 0 {
+		arg := args[0]
+		args = args[1:]
+		if arg == "--" {
+			return append(positional, args...), nil
+		}
+		// Negative numbers are arguments for the program, not flags
+		if _, err := strconv.ParseFloat(arg, 64); len(arg) > 1 && arg[0] == '-' && err != nil {
+			parsed := append([]string{arg}, args...)
+			if err := flags.Parse(parsed); err != nil {
+				return nil, err
+			}
+			args = flags.Args()
+			// The flag package stops at "--" and drops it, so everything
+			// left after it belongs to the program
+			if consumed := parsed[:len(parsed)-len(args)]; consumed[len(consumed)-1] == "--" {
+				return append(positional, args...), nil
+			}
+			continue
+		}
+		positional = append(positional, arg)
+	}
+	return positional, nil
+}
+
 func main() {
 
-	code := testcode.GetProgram()
+	legacy := flag.Bool("legacy", false, "accept older programs that end statements with a newline instead of ';', warning about each one")
+	checked := flag.Bool("checked", false, "report signed integer overflow as a runtime error instead of wrapping")
+	bigIntegers := flag.Bool("big", false, "use arbitrary-precision integers that never overflow")
+	noAssert := flag.Bool("no-assert", false, "skip assert statements, e.g. for benchmark runs")
+	maxSteps := flag.Int("max-steps", 0, "stop the program after this many statements and loop iterations (0 for no limit)")
+	timeout := flag.Duration("timeout", 0, "stop the program after it has run for this long, e.g. 5s (0 for no limit)")
+	maxVariables := flag.Int("max-variables", 0, "limit the number of variables a program can declare (0 for no limit)")
+	maxOutput := flag.Int("max-output", 0, "limit the number of bytes a program can print (0 for no limit)")
+	maxDepth := flag.Int("max-depth", parser.DEFAULTMAXDEPTH, "limit how deeply expressions and blocks can be nested (0 for no limit)")
+	var variables defines
+	flag.Var(&variables, "D", "set a variable before the program runs, e.g. -D val=27 (can be repeated)")
+	varsFile := flag.String("vars", "", "set variables from a JSON object in this file before the program runs")
+	dumpFile := flag.String("dump-vars", "", "write the variables to this file as JSON after the program runs")
+	fileRoot := flag.String("fs-root", "", "let programs open files in this directory (file access is disabled without it)")
 
-	lexedCode := lexer.CreateLexer(code)
+	// "run program.cmm arguments..." runs a program from a file and passes
+	// it the arguments after its name, otherwise the sample program is run
+	run, args, err := parseCommandLine(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	code := testcode.GetProgram()
+	var programArgs []string
+	if run {
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: go-interpreter [flags] run [flags] program.cmm [arguments]")
+			os.Exit(2)
+		}
+		source, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		code = string(source)
+		programArgs = args[1:]
+	}
 
-	program := parser.CreateParser(lexedCode)
-	parsedProgram := program.ParseProgram()
+	programInterpreter := interpreter.CreateInterpreter(os.Stdin, os.Stdout, os.Stderr)
+	programInterpreter.LegacyMode = *legacy
+	programInterpreter.CheckedArithmetic = *checked
+	programInterpreter.BigIntegers = *bigIntegers
+	programInterpreter.DisableAssertions = *noAssert
+	programInterpreter.MaxSteps = *maxSteps
+	programInterpreter.Timeout = *timeout
+	programInterpreter.MaxVariables = *maxVariables
+	programInterpreter.MaxOutput = *maxOutput
+	programInterpreter.MaxDepth = *maxDepth
+	programInterpreter.Args = programArgs
+	programInterpreter.FileRoot = *fileRoot
 
-	// Check length of program.GetErrors before continuing
-	// to see if any syntax errors occurred
-	errors := program.GetErrors()
-	for _, err := range errors {
-		fmt.Println(err)
+	// Variables from --vars are set first so -D can override them
+	if *varsFile != "" {
+		file, err := os.Open(*varsFile)
+		if err == nil {
+			err = programInterpreter.LoadVariables(file)
+			file.Close()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	for _, variable := range variables {
+		name, value, _ := strings.Cut(variable, "=")
+		if err := programInterpreter.Define(name, value); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 	}
 
-	if len(errors) == 0 {
-		symbolTable := symbol.CreateSymbolTable()
-		evaluated := evaluator.Evaluate(parsedProgram, symbolTable)
-		fmt.Print(evaluated.GetValue())
+	// Ctrl+C stops the program cleanly rather than killing the interpreter
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Errors have already been written to stderr by the interpreter,
+	// the exit code tells the shell whether the program succeeded
+	result, _ := programInterpreter.Run(ctx, code)
+	stop()
+
+	if *dumpFile != "" {
+		file, err := os.Create(*dumpFile)
+		if err == nil {
+			err = programInterpreter.DumpVariables(file)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 	}
+	os.Exit(result.ExitCode)
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestParseCommandLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		run      bool
+		runArgs  []string
+		big      bool
+		maxSteps int
+		defines  []string
+	}{
+		{"sample program", nil, false, nil, false, 0, nil},
+		{"flags without run", []string{"-big"}, false, nil, true, 0, nil},
+		{"program and arguments", []string{"run", "a.cmm", "1", "x"}, true, []string{"a.cmm", "1", "x"}, false, 0, nil},
+		{"run without a program", []string{"run"}, true, []string{}, false, 0, nil},
+		{"flags before run", []string{"-big", "-max-steps", "10", "run", "a.cmm"}, true, []string{"a.cmm"}, true, 10, nil},
+		{"flags after run", []string{"run", "-big", "-max-steps=10", "a.cmm", "1"}, true, []string{"a.cmm", "1"}, true, 10, nil},
+		{"flags after the program", []string{"run", "a.cmm", "-big", "1"}, true, []string{"a.cmm", "1"}, true, 0, nil},
+		{"flags before and after run", []string{"-big", "run", "-max-steps", "5", "a.cmm"}, true, []string{"a.cmm"}, true, 5, nil},
+		{"dashes after run", []string{"run", "--", "a.cmm", "-big"}, true, []string{"a.cmm", "-big"}, false, 0, nil},
+		{"dashes after the program", []string{"run", "a.cmm", "--", "-big", "--"}, true, []string{"a.cmm", "-big", "--"}, false, 0, nil},
+		{"dashes after a flag", []string{"run", "-big", "--", "a.cmm", "-max-steps", "3"}, true, []string{"a.cmm", "-max-steps", "3"}, true, 0, nil},
+		{"dashes after a flag before run", []string{"-big", "--", "run", "a.cmm", "--", "-x"}, true, []string{"a.cmm", "-x"}, true, 0, nil},
+		{"negative numbers", []string{"run", "a.cmm", "-5", "-2.5", "-1e3"}, true, []string{"a.cmm", "-5", "-2.5", "-1e3"}, false, 0, nil},
+		{"negative number after a flag", []string{"run", "-big", "a.cmm", "-5"}, true, []string{"a.cmm", "-5"}, true, 0, nil},
+		{"lone dash", []string{"run", "a.cmm", "-"}, true, []string{"a.cmm", "-"}, false, 0, nil},
+		{"defines", []string{"-D", "a=1", "run", "-D", "b=-2", "a.cmm", "-D=c=x y"}, true, []string{"a.cmm"}, false, 0, []string{"a=1", "b=-2", "c=x y"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags, big, maxSteps, variables := createTestFlags()
+			run, runArgs, err := parseCommandLine(flags, test.args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if run != test.run || !reflect.DeepEqual(runArgs, test.runArgs) {
+				t.Errorf("got run %v with %q, want run %v with %q", run, runArgs, test.run, test.runArgs)
+			}
+			if *big != test.big || *maxSteps != test.maxSteps {
+				t.Errorf("got -big=%v -max-steps=%d, want -big=%v -max-steps=%d", *big, *maxSteps, test.big, test.maxSteps)
+			}
+			if []string(*variables) == nil && test.defines == nil {
+				return
+			}
+			if !reflect.DeepEqual([]string(*variables), test.defines) {
+				t.Errorf("got -D values %q, want %q", *variables, test.defines)
+			}
+		})
+	}
+}
+
+func TestParseCommandLineErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"argument without run", []string{"a.cmm"}},
+		{"argument after flags without run", []string{"-big", "a.cmm"}},
+		{"unknown flag after run", []string{"run", "a.cmm", "-nope"}},
+		{"missing flag value after run", []string{"run", "a.cmm", "-max-steps"}},
+		{"define without a value", []string{"run", "-D", "a", "a.cmm"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags, _, _, _ := createTestFlags()
+			if run, _, err := parseCommandLine(flags, test.args); err == nil || run {
+				t.Fatalf("got run %v and no error, want an error", run)
+			}
+		})
+	}
+}
+
+// Create a flag set with some of the flags main defines
+func createTestFlags() (*flag.FlagSet, *bool, *int, *defines) {
+	flags := flag.NewFlagSet("go-interpreter", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	big := flags.Bool("big", false, "")
+	maxSteps := flags.Int("max-steps", 0, "")
+	var variables defines
+	flags.Var(&variables, "D", "")
+	return flags, big, maxSteps, &variables
+}